@@ -6,8 +6,8 @@ export GOARCH=arm64
 OUTPUT_DIR="./app"
 mkdir -p "$OUTPUT_DIR"
 
-PROGRAMS=("extract_metrics" "metrics_loadgen" "k8s_merge" "node_loadgen")
-PROGRAM_PATHS=("src/extract_metrics" "src/metrics_loadgen" "src/k8s_merge" "src/node_loadgen")
+PROGRAMS=("extract_metrics" "metrics_loadgen" "k8s_merge" "node_loadgen" "traces_loadgen" "logs_loadgen" "otlp_sink")
+PROGRAM_PATHS=("src/extract_metrics" "src/metrics_loadgen" "src/k8s_merge" "src/node_loadgen" "src/traces_loadgen" "src/logs_loadgen" "src/otlp_sink")
 MAIN_FILES=("extract_metrics_main.go" "metrics_loadgen_main.go" k8s_merge)
 
 for i in "${!PROGRAMS[@]}"; do
@@ -0,0 +1,55 @@
+package common
+
+import (
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// AttrValue models the OTLP AnyValue union. At most one field is set; a value with none of
+// the typed fields set is a string (possibly empty), which keeps the common string case a
+// plain field that code can read and rewrite directly.
+type AttrValue struct {
+	StringValue string        `json:"stringValue,omitempty"`
+	BoolValue   *bool         `json:"boolValue,omitempty"`
+	IntValue    *Int64String  `json:"intValue,omitempty"`
+	DoubleValue *float64      `json:"doubleValue,omitempty"`
+	ArrayValue  *ArrayValue   `json:"arrayValue,omitempty"`
+	KvlistValue *KeyValueList `json:"kvlistValue,omitempty"`
+	BytesValue  []byte        `json:"bytesValue,omitempty"` // base64 in OTLP/JSON, like encoding/json
+}
+
+type ArrayValue struct {
+	Values []AttrValue `json:"values"`
+}
+
+type KeyValueList struct {
+	Values []Attribute `json:"values"`
+}
+
+// IsString reports whether none of the typed fields are set
+func (v AttrValue) IsString() bool {
+	return v.BoolValue == nil && v.IntValue == nil && v.DoubleValue == nil && v.ArrayValue == nil && v.KvlistValue == nil && v.BytesValue == nil
+}
+
+// ToOTLPAnyValue converts an AttrValue to its protobuf form without losing its type
+func ToOTLPAnyValue(v AttrValue) *commonpb.AnyValue {
+	switch {
+	case v.BoolValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: *v.BoolValue}}
+	case v.IntValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(*v.IntValue)}}
+	case v.DoubleValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: *v.DoubleValue}}
+	case v.ArrayValue != nil:
+		values := make([]*commonpb.AnyValue, 0, len(v.ArrayValue.Values))
+		for _, item := range v.ArrayValue.Values {
+			values = append(values, ToOTLPAnyValue(item))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case v.KvlistValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: ToOTLPAttributes(v.KvlistValue.Values)}}}
+	case v.BytesValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v.BytesValue}}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.StringValue}}
+	}
+}
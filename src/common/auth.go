@@ -0,0 +1,219 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Secret is a value given literally, or read from an environment variable or a file
+type Secret struct {
+	Value string `yaml:"value"` // Literal; ${ENV} substitution works here as everywhere
+	Env   string `yaml:"env"`   // Name of an environment variable
+	File  string `yaml:"file"`  // File whose content is used, surrounding whitespace trimmed
+}
+
+// IsSet reports whether any source is configured
+func (s Secret) IsSet() bool {
+	return s.Value != "" || s.Env != "" || s.File != ""
+}
+
+// Validate checks that at most one source is set, without reading it
+func (s Secret) Validate() error {
+	sources := 0
+	for _, source := range []string{s.Value, s.Env, s.File} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("set only one of value, env and file")
+	}
+	return nil
+}
+
+// Resolve returns the secret from its source. Files and variables are read on every call,
+// so rotated secrets are picked up whenever the senders are recreated.
+func (s Secret) Resolve() (string, error) {
+	if err := s.Validate(); err != nil {
+		return "", err
+	}
+	switch {
+	case s.Env != "":
+		value, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", s.Env)
+		}
+		return value, nil
+	case s.File != "":
+		path, err := ExpandPath(s.File)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return s.Value, nil
+	}
+}
+
+// HeaderConfig is one entry of the headers list in config.yaml, e.g. X-SF-Token
+type HeaderConfig struct {
+	Name   string `yaml:"name"`
+	Secret `yaml:",inline"`
+}
+
+// Validate checks that the header has a name and a single secret source
+func (h HeaderConfig) Validate() error {
+	if h.Name == "" {
+		return fmt.Errorf("header without a name")
+	}
+	return h.Secret.Validate()
+}
+
+// AuthConfig is the auth section of config.yaml; it sets the Authorization header
+type AuthConfig struct {
+	Bearer   Secret `yaml:"bearer"`   // Authorization: Bearer <token>
+	Username string `yaml:"username"` // Authorization: Basic, together with password
+	Password Secret `yaml:"password"`
+}
+
+// Validate checks that only one kind of credentials is configured. Secrets are only read
+// when the senders are created.
+func (c AuthConfig) Validate() error {
+	if c.Bearer.IsSet() && c.Username != "" {
+		return fmt.Errorf("set either bearer or username and password, not both")
+	}
+	if c.Password.IsSet() && c.Username == "" {
+		return fmt.Errorf("password needs a username")
+	}
+	if err := c.Bearer.Validate(); err != nil {
+		return fmt.Errorf("bearer: %w", err)
+	}
+	if err := c.Password.Validate(); err != nil {
+		return fmt.Errorf("password: %w", err)
+	}
+	return nil
+}
+
+// TLSConfig is the tls section of config.yaml, used for https collector URLs and for gRPC
+// unless grpc_insecure is set
+type TLSConfig struct {
+	CAFile             string `yaml:"ca_file"`              // CA bundle verifying the collector; system roots if empty
+	CertFile           string `yaml:"cert_file"`            // Client certificate for mTLS, with key_file
+	KeyFile            string `yaml:"key_file"`             // Client key for mTLS
+	ServerName         string `yaml:"server_name"`          // Overrides the name the certificate is checked against
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"` // Accepts any certificate; testing only
+}
+
+// Validate checks that client certificate and key come together
+func (c TLSConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// Build loads the certificates into a tls.Config
+func (c TLSConfig) Build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		path, err := ExpandPath(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.CertFile != "" {
+		certFile, err := ExpandPath(c.CertFile)
+		if err != nil {
+			return nil, err
+		}
+		keyFile, err := ExpandPath(c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// senderSettings are the headers and TLS settings shared by every exporter
+type senderSettings struct {
+	headers map[string]string
+	tls     *tls.Config
+}
+
+// newSenderSettings resolves the configured headers, credentials and certificates
+func newSenderSettings() (senderSettings, error) {
+	headers, err := resolveHeaders(Headers, Auth)
+	if err != nil {
+		return senderSettings{}, err
+	}
+	tlsSettings := TLS
+	if tlsSettings.CAFile == "" {
+		tlsSettings.CAFile = GRPCCAFile
+	}
+	tlsConfig, err := tlsSettings.Build()
+	if err != nil {
+		return senderSettings{}, err
+	}
+	return senderSettings{headers: headers, tls: tlsConfig}, nil
+}
+
+// resolveHeaders returns the static headers plus the Authorization header, with every
+// secret read from its source
+func resolveHeaders(headers []HeaderConfig, auth AuthConfig) (map[string]string, error) {
+	resolved := make(map[string]string, len(headers)+1)
+	for _, header := range headers {
+		if err := header.Validate(); err != nil {
+			return nil, err
+		}
+		value, err := header.Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", header.Name, err)
+		}
+		resolved[header.Name] = value
+	}
+
+	if err := auth.Validate(); err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	if auth.Bearer.IsSet() {
+		token, err := auth.Bearer.Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("auth bearer: %w", err)
+		}
+		resolved["Authorization"] = "Bearer " + token
+	}
+	if auth.Username != "" {
+		password, err := auth.Password.Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("auth password: %w", err)
+		}
+		resolved["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+password))
+	}
+	return resolved, nil
+}
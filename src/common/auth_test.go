@@ -0,0 +1,206 @@
+package common
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testPKI is a CA with one client certificate signed by it, written to PEM files
+type testPKI struct {
+	pool              *x509.CertPool
+	certFile, keyFile string
+	caFile            string // The CA itself, for the server to verify clients with
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	newKey := func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	caKey := newKey()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	clientKey := newKey()
+	clientDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "loadgen"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, caCert, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKeyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return testPKI{
+		pool:     pool,
+		certFile: writePEM("client.pem", "CERTIFICATE", clientDER),
+		keyFile:  writePEM("client-key.pem", "EC PRIVATE KEY", clientKeyDER),
+		caFile:   writePEM("ca.pem", "CERTIFICATE", caDER),
+	}
+}
+
+// startTLSCollector serves OTLP/HTTP over TLS and returns the server, a CA file trusting it
+// and the headers of the last request
+func startTLSCollector(t *testing.T, clientCAs *x509.CertPool) (*httptest.Server, string, *http.Header) {
+	t.Helper()
+	var last http.Header
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	if clientCAs != nil {
+		server.TLS = &tls.Config{ClientCAs: clientCAs, ClientAuth: tls.RequireAndVerifyClientCert}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "server-ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return server, caFile, &last
+}
+
+// exportWith builds the sender settings from the given globals and sends one request
+func exportWith(t *testing.T, url string, tlsConfig TLSConfig, headers []HeaderConfig, auth AuthConfig) error {
+	t.Helper()
+	oldTLS, oldHeaders, oldAuth, oldCA := TLS, Headers, Auth, GRPCCAFile
+	t.Cleanup(func() { TLS, Headers, Auth, GRPCCAFile = oldTLS, oldHeaders, oldAuth, oldCA })
+	TLS, Headers, Auth, GRPCCAFile = tlsConfig, headers, auth, ""
+
+	settings, err := newSenderSettings()
+	if err != nil {
+		return err
+	}
+	return newHTTPExporter(url, ProtocolHTTPJSON, CompressionNone, 5*time.Second, settings).Export(context.Background(), testMetricsRequest("tls"))
+}
+
+func TestTLSCABundle(t *testing.T) {
+	server, caFile, _ := startTLSCollector(t, nil)
+
+	if err := exportWith(t, server.URL, TLSConfig{}, nil, AuthConfig{}); err == nil {
+		t.Error("export succeeded without trusting the server's CA")
+	}
+	if err := exportWith(t, server.URL, TLSConfig{CAFile: caFile}, nil, AuthConfig{}); err != nil {
+		t.Errorf("export with ca_file: %v", err)
+	}
+	if err := exportWith(t, server.URL, TLSConfig{InsecureSkipVerify: true}, nil, AuthConfig{}); err != nil {
+		t.Errorf("export with insecure_skip_verify: %v", err)
+	}
+}
+
+func TestTLSServerName(t *testing.T) {
+	server, caFile, _ := startTLSCollector(t, nil)
+
+	// The httptest certificate is valid for example.com, not for any other name
+	if err := exportWith(t, server.URL, TLSConfig{CAFile: caFile, ServerName: "example.com"}, nil, AuthConfig{}); err != nil {
+		t.Errorf("export with server_name example.com: %v", err)
+	}
+	if err := exportWith(t, server.URL, TLSConfig{CAFile: caFile, ServerName: "collector.test"}, nil, AuthConfig{}); err == nil {
+		t.Error("export succeeded although server_name does not match the certificate")
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	pki := newTestPKI(t)
+	server, caFile, _ := startTLSCollector(t, pki.pool)
+
+	if err := exportWith(t, server.URL, TLSConfig{CAFile: caFile}, nil, AuthConfig{}); err == nil {
+		t.Error("export succeeded without a client certificate")
+	}
+	if err := exportWith(t, server.URL, TLSConfig{CAFile: caFile, CertFile: pki.certFile, KeyFile: pki.keyFile}, nil, AuthConfig{}); err != nil {
+		t.Errorf("export with client certificate: %v", err)
+	}
+	// The CA certificate paired with the client key is a mismatch Build must catch
+	if _, err := (TLSConfig{CertFile: pki.caFile, KeyFile: pki.keyFile}).Build(); err == nil {
+		t.Error("Build accepted a certificate that does not match its key")
+	}
+}
+
+func TestAuthHeaders(t *testing.T) {
+	server, caFile, last := startTLSCollector(t, nil)
+	t.Setenv("LG_TEST_TOKEN", "env-token")
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(" file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig := TLSConfig{CAFile: caFile}
+	headers := []HeaderConfig{
+		{Name: "X-SF-Token", Secret: Secret{Env: "LG_TEST_TOKEN"}},
+		{Name: "X-Scope-OrgID", Secret: Secret{Value: "tenant-1"}},
+	}
+
+	if err := exportWith(t, server.URL, tlsConfig, headers, AuthConfig{Bearer: Secret{File: tokenFile}}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"X-SF-Token":    "env-token",
+		"X-Scope-OrgID": "tenant-1",
+		"Authorization": "Bearer file-token",
+	} {
+		if got := last.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	if err := exportWith(t, server.URL, tlsConfig, nil, AuthConfig{Username: "loadgen", Password: Secret{Value: "s3cret"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("loadgen:s3cret"))
+	if got := last.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+
+	if err := exportWith(t, server.URL, tlsConfig, []HeaderConfig{{Name: "X-SF-Token", Secret: Secret{Env: "LG_TEST_UNSET_TOKEN"}}}, AuthConfig{}); err == nil {
+		t.Error("sender settings resolved although the token variable is not set")
+	}
+}
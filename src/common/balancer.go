@@ -0,0 +1,121 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+
+	logscollectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	tracecollectorpb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Supported balancing strategies across several collector endpoints
+const (
+	BalanceRoundRobin = "round_robin" // Every request goes to the next endpoint
+	BalanceHash       = "hash"        // Requests stick to an endpoint by a resource attribute
+	BalanceBroadcast  = "broadcast"   // Every request goes to all endpoints
+)
+
+// BalancingConfig is the balancing section of config.yaml
+type BalancingConfig struct {
+	Strategy      string `yaml:"strategy"`
+	HashAttribute string `yaml:"hash_attribute"` // Resource attribute the hash strategy routes by
+}
+
+// Validate checks the strategy and that hash has an attribute to route by
+func (c BalancingConfig) Validate() error {
+	switch c.Strategy {
+	case BalanceRoundRobin, BalanceBroadcast:
+	case BalanceHash:
+		if c.HashAttribute == "" {
+			return fmt.Errorf("hash strategy needs a hash_attribute")
+		}
+	default:
+		return fmt.Errorf("unknown strategy %q (must be %s, %s or %s)", c.Strategy, BalanceRoundRobin, BalanceHash, BalanceBroadcast)
+	}
+	return nil
+}
+
+// newBalancer spreads requests over one sink per endpoint as configured
+func newBalancer(sinks []Sink, endpoints []string, cfg BalancingConfig) Sink {
+	switch cfg.Strategy {
+	case BalanceBroadcast:
+		return &teeSink{sinks: sinks}
+	case BalanceHash:
+		return &hashSink{roundRobinSink: roundRobinSink{sinks: sinks}, endpoints: endpoints, attribute: cfg.HashAttribute}
+	default:
+		return &roundRobinSink{sinks: sinks}
+	}
+}
+
+// roundRobinSink sends every request to the next of its sinks
+type roundRobinSink struct {
+	sinks []Sink
+	next  atomic.Uint64
+}
+
+func (s *roundRobinSink) Export(ctx context.Context, req proto.Message) error {
+	i := (s.next.Add(1) - 1) % uint64(len(s.sinks))
+	return s.sinks[i].Export(ctx, req)
+}
+
+func (s *roundRobinSink) Close() error {
+	return (&teeSink{sinks: s.sinks}).Close()
+}
+
+// hashSink routes every request by a resource attribute with rendezvous hashing: each
+// value goes to the endpoint with the highest hash of endpoint and value. Adding or
+// removing an endpoint only moves the values of that endpoint. Requests without the
+// attribute are sent round-robin.
+type hashSink struct {
+	roundRobinSink
+	endpoints []string
+	attribute string
+}
+
+func (s *hashSink) Export(ctx context.Context, req proto.Message) error {
+	value, ok := resourceAttribute(req, s.attribute)
+	if !ok {
+		Debugf("No %s on the resource, sending round-robin", s.attribute)
+		return s.roundRobinSink.Export(ctx, req)
+	}
+	best, bestScore := 0, uint64(0)
+	for i, endpoint := range s.endpoints {
+		sum := sha256.Sum256([]byte(endpoint + "\x00" + value))
+		if score := binary.BigEndian.Uint64(sum[:8]); i == 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return s.sinks[best].Export(ctx, req)
+}
+
+// resourceAttribute returns a string attribute of the first resource of an export request
+func resourceAttribute(req proto.Message, key string) (string, bool) {
+	var attrs []*commonpb.KeyValue
+	switch r := req.(type) {
+	case *collectorpb.ExportMetricsServiceRequest:
+		if len(r.ResourceMetrics) > 0 {
+			attrs = r.ResourceMetrics[0].GetResource().GetAttributes()
+		}
+	case *tracecollectorpb.ExportTraceServiceRequest:
+		if len(r.ResourceSpans) > 0 {
+			attrs = r.ResourceSpans[0].GetResource().GetAttributes()
+		}
+	case *logscollectorpb.ExportLogsServiceRequest:
+		if len(r.ResourceLogs) > 0 {
+			attrs = r.ResourceLogs[0].GetResource().GetAttributes()
+		}
+	}
+	for _, kv := range attrs {
+		if kv.Key == key {
+			value := kv.GetValue().GetStringValue()
+			return value, value != ""
+		}
+	}
+	return "", false
+}
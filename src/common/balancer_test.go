@@ -0,0 +1,124 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// countingSink remembers the cluster of every request it received
+type countingSink struct {
+	clusters []string
+}
+
+func (s *countingSink) Export(_ context.Context, req proto.Message) error {
+	cluster, _ := resourceAttribute(req, "k8s.cluster.name")
+	s.clusters = append(s.clusters, cluster)
+	return nil
+}
+
+func (s *countingSink) Close() error { return nil }
+
+func clusterRequest(cluster string) *collectorpb.ExportMetricsServiceRequest {
+	req := testMetricsRequest("balanced")
+	if cluster != "" {
+		req.ResourceMetrics[0].Resource = &resourcepb.Resource{Attributes: []*commonpb.KeyValue{{
+			Key:   "k8s.cluster.name",
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: cluster}},
+		}}}
+	}
+	return req
+}
+
+func newTestBalancer(endpoints []string, strategy string) (Sink, []*countingSink) {
+	counting := make([]*countingSink, len(endpoints))
+	sinks := make([]Sink, len(endpoints))
+	for i := range endpoints {
+		counting[i] = &countingSink{}
+		sinks[i] = counting[i]
+	}
+	return newBalancer(sinks, endpoints, BalancingConfig{Strategy: strategy, HashAttribute: "k8s.cluster.name"}), counting
+}
+
+// hashRoutes sends two requests per cluster and returns the endpoint each cluster went to
+func hashRoutes(t *testing.T, endpoints []string, clusters int) map[string]string {
+	t.Helper()
+	balancer, counting := newTestBalancer(endpoints, BalanceHash)
+	for round := 0; round < 2; round++ {
+		for c := 0; c < clusters; c++ {
+			balancer.Export(context.Background(), clusterRequest(fmt.Sprintf("cluster-%02d", c)))
+		}
+	}
+	routes := make(map[string]string)
+	for i, sink := range counting {
+		for _, cluster := range sink.clusters {
+			if previous, ok := routes[cluster]; ok && previous != endpoints[i] {
+				t.Fatalf("%s went to %s and %s", cluster, previous, endpoints[i])
+			}
+			routes[cluster] = endpoints[i]
+		}
+	}
+	return routes
+}
+
+func TestHashBalancingIsSticky(t *testing.T) {
+	endpoints := []string{"http://gw-1:4318", "http://gw-2:4318", "http://gw-3:4318"}
+	routes := hashRoutes(t, endpoints, 60)
+
+	perEndpoint := make(map[string]int)
+	for _, endpoint := range routes {
+		perEndpoint[endpoint]++
+	}
+	for _, endpoint := range endpoints {
+		if perEndpoint[endpoint] < 5 {
+			t.Errorf("%s got %d of 60 clusters, want them spread over all endpoints", endpoint, perEndpoint[endpoint])
+		}
+	}
+}
+
+func TestHashBalancingMovesOnlyRemovedEndpoint(t *testing.T) {
+	endpoints := []string{"http://gw-1:4318", "http://gw-2:4318", "http://gw-3:4318"}
+	before := hashRoutes(t, endpoints, 60)
+	after := hashRoutes(t, endpoints[:2], 60)
+
+	for cluster, endpoint := range before {
+		if endpoint != endpoints[2] && after[cluster] != endpoint {
+			t.Errorf("%s moved from %s to %s although its endpoint stayed", cluster, endpoint, after[cluster])
+		}
+	}
+}
+
+func TestHashBalancingWithoutAttribute(t *testing.T) {
+	balancer, counting := newTestBalancer([]string{"a", "b"}, BalanceHash)
+	for i := 0; i < 4; i++ {
+		balancer.Export(context.Background(), clusterRequest(""))
+	}
+	if len(counting[0].clusters) != 2 || len(counting[1].clusters) != 2 {
+		t.Errorf("got %d and %d requests, want round-robin without the attribute", len(counting[0].clusters), len(counting[1].clusters))
+	}
+}
+
+func TestRoundRobinAndBroadcast(t *testing.T) {
+	balancer, counting := newTestBalancer([]string{"a", "b", "c"}, BalanceRoundRobin)
+	for i := 0; i < 6; i++ {
+		balancer.Export(context.Background(), clusterRequest("demo"))
+	}
+	for i, sink := range counting {
+		if len(sink.clusters) != 2 {
+			t.Errorf("round_robin: endpoint %d got %d requests, want 2", i, len(sink.clusters))
+		}
+	}
+
+	balancer, counting = newTestBalancer([]string{"a", "b", "c"}, BalanceBroadcast)
+	balancer.Export(context.Background(), clusterRequest("demo"))
+	for i, sink := range counting {
+		if len(sink.clusters) != 1 {
+			t.Errorf("broadcast: endpoint %d got %d requests, want 1", i, len(sink.clusters))
+		}
+	}
+}
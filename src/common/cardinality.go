@@ -0,0 +1,155 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Cardinality multiplies the resources of every replica below the cluster level. Together
+// with no_replicas it models one large cluster (1 replica, many nodes) as well as many
+// small ones (many replicas, few nodes).
+type Cardinality struct {
+	NodesPerCluster  int `yaml:"nodes_per_cluster"`  // Clones of every captured node
+	PodsPerNode      int `yaml:"pods_per_node"`      // Clones of every captured pod, per node clone
+	ContainersPerPod int `yaml:"containers_per_pod"` // Clones of every captured container, per pod clone
+}
+
+// Validate checks the multipliers; 0 is treated as 1
+func (c Cardinality) Validate() error {
+	if c.NodesPerCluster < 0 || c.PodsPerNode < 0 || c.ContainersPerPod < 0 {
+		return fmt.Errorf("multipliers must be >= 0, got %d nodes, %d pods, %d containers", c.NodesPerCluster, c.PodsPerNode, c.ContainersPerPod)
+	}
+	return nil
+}
+
+// Enabled reports whether any level is multiplied
+func (c Cardinality) Enabled() bool {
+	return c.NodesPerCluster > 1 || c.PodsPerNode > 1 || c.ContainersPerPod > 1
+}
+
+// Resource levels, from the least to the most specific
+const (
+	levelCluster = iota
+	levelNode
+	levelPod
+	levelContainer
+)
+
+// Attributes rewritten per level. Names get an ordinal suffix, IDs are replaced by a
+// deterministic hash in the same format as the original.
+var (
+	cardinalityNames = map[string]int{
+		"k8s.node.name":      levelNode,
+		"host.name":          levelNode,
+		"k8s.pod.name":       levelPod,
+		"k8s.container.name": levelContainer,
+	}
+	cardinalityIDs = map[string]int{
+		"k8s.node.uid": levelNode,
+		"host.id":      levelNode,
+		"k8s.pod.uid":  levelPod,
+		"container.id": levelContainer,
+	}
+)
+
+// resourceLevel returns the most specific level a resource describes
+func resourceLevel(resource Resource) int {
+	level := levelCluster
+	for _, attr := range resource.Attributes {
+		if l, ok := cardinalityNames[attr.Key]; ok && l > level {
+			level = l
+		}
+		if l, ok := cardinalityIDs[attr.Key]; ok && l > level {
+			level = l
+		}
+	}
+	return level
+}
+
+// ExpandCardinality clones every node, pod and container resource of the file by the
+// configured multipliers, so it must run before anything that keys state on positions.
+func ExpandCardinality(metricsFile *MetricsFile, c Cardinality) {
+	if !c.Enabled() {
+		return
+	}
+	nodes, pods, containers := max(c.NodesPerCluster, 1), max(c.PodsPerNode, 1), max(c.ContainersPerPod, 1)
+
+	var expanded []ResourceMetric
+	for _, rm := range metricsFile.ResourceMetrics {
+		level := resourceLevel(rm.Resource)
+		if level == levelCluster {
+			expanded = append(expanded, rm)
+			continue
+		}
+
+		// A resource is cloned for every copy of its own level and the levels above it
+		podsFor, containersFor := 1, 1
+		if level >= levelPod {
+			podsFor = pods
+		}
+		if level >= levelContainer {
+			containersFor = containers
+		}
+		for n := 0; n < nodes; n++ {
+			for p := 0; p < podsFor; p++ {
+				for k := 0; k < containersFor; k++ {
+					clone := deepCopyJSON(rm)
+					ordinals := [...]int{levelNode: n, levelPod: n*podsFor + p, levelContainer: (n*podsFor+p)*containersFor + k}
+					copies := [...]int{levelNode: nodes, levelPod: nodes * podsFor, levelContainer: nodes * podsFor * containersFor}
+					rewriteCardinality(&clone.Resource, ordinals[:], copies[:], k, containersFor)
+					expanded = append(expanded, clone)
+				}
+			}
+		}
+	}
+	metricsFile.ResourceMetrics = expanded
+}
+
+func rewriteCardinality(resource *Resource, ordinals []int, copies []int, container int, containers int) {
+	for i, attr := range resource.Attributes {
+		val := attr.Value.StringValue
+		if val == "" {
+			continue
+		}
+		if level, ok := cardinalityNames[attr.Key]; ok {
+			switch {
+			case level == levelContainer:
+				// Container names only have to be unique within their pod
+				if containers > 1 {
+					resource.Attributes[i].Value.StringValue = fmt.Sprintf("%s-%02d", val, container)
+				}
+			case copies[level] > 1:
+				resource.Attributes[i].Value.StringValue = fmt.Sprintf("%s-%02d", val, ordinals[level])
+			}
+		}
+		if level, ok := cardinalityIDs[attr.Key]; ok && copies[level] > 1 {
+			resource.Attributes[i].Value.StringValue = deriveID(val, ordinals[level])
+		}
+	}
+}
+
+// deriveID returns a stable ID for the given copy that keeps the layout of the original,
+// e.g. the dashes of a UUID or a containerd:// prefix, so the first characters differ
+// between copies
+func deriveID(val string, ordinal int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%d", val, ordinal)))
+	digits := hex.EncodeToString(sum[:])
+
+	var id strings.Builder
+	if idx := strings.Index(val, "://"); idx >= 0 {
+		id.WriteString(val[:idx+3])
+		val = val[idx+3:]
+	}
+	next := 0
+	for _, ch := range val {
+		if ch == '-' || ch == ':' || ch == '/' {
+			id.WriteRune(ch)
+			continue
+		}
+		id.WriteByte(digits[next%len(digits)])
+		next++
+	}
+	return id.String()
+}
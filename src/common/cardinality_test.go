@@ -0,0 +1,114 @@
+package common
+
+import (
+	"regexp"
+	"testing"
+)
+
+// testResource builds a resource from key/value pairs
+func testResource(kv ...string) Resource {
+	var resource Resource
+	for i := 0; i+1 < len(kv); i += 2 {
+		resource.Attributes = append(resource.Attributes, Attribute{Key: kv[i], Value: AttrValue{StringValue: kv[i+1]}})
+	}
+	return resource
+}
+
+func attribute(resource Resource, key string) string {
+	for _, attr := range resource.Attributes {
+		if attr.Key == key {
+			return attr.Value.StringValue
+		}
+	}
+	return ""
+}
+
+// cardinalityCapture has one cluster, one node, two pods and one container resource
+func cardinalityCapture() *MetricsFile {
+	var file MetricsFile
+	for _, resource := range []Resource{
+		testResource("k8s.cluster.name", "prod"),
+		testResource("k8s.node.name", "node-a", "k8s.node.uid", "7f8e3d2a-1b4c-4d5e-8f9a-0b1c2d3e4f5a"),
+		testResource("k8s.node.name", "node-a", "k8s.pod.name", "api", "k8s.pod.uid", "11111111-2222-3333-4444-555555555555"),
+		testResource("k8s.node.name", "node-a", "k8s.pod.name", "db", "k8s.pod.uid", "66666666-7777-8888-9999-000000000000"),
+		testResource("k8s.node.name", "node-a", "k8s.pod.name", "api", "k8s.container.name", "app", "container.id", "containerd://0123456789abcdef"),
+	} {
+		file.ResourceMetrics = append(file.ResourceMetrics, ResourceMetric{Resource: resource})
+	}
+	return &file
+}
+
+func TestExpandCardinalityCounts(t *testing.T) {
+	for _, tt := range []struct {
+		name                             string
+		cardinality                      Cardinality
+		resources                        int
+		nodes, pods, podUIDs, containers int // Distinct values after expansion
+	}{
+		{"disabled", Cardinality{}, 5, 1, 2, 2, 1},
+		{"all ones", Cardinality{NodesPerCluster: 1, PodsPerNode: 1, ContainersPerPod: 1}, 5, 1, 2, 2, 1},
+		// 1 cluster + 3 nodes + 3 nodes x 2 pods + 3 nodes x 1 container
+		{"nodes", Cardinality{NodesPerCluster: 3}, 13, 3, 6, 6, 3},
+		// 1 cluster + 1 node + 4 clones x 2 pods + 4 clones x 1 container
+		{"pods", Cardinality{PodsPerNode: 4}, 14, 1, 8, 8, 4},
+		// 1 cluster + 2 nodes + 2 x 3 x 2 pods + 2 x 3 x 2 containers
+		{"all levels", Cardinality{NodesPerCluster: 2, PodsPerNode: 3, ContainersPerPod: 2}, 27, 2, 12, 12, 12},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			file := cardinalityCapture()
+			ExpandCardinality(file, tt.cardinality)
+			if got := len(file.ResourceMetrics); got != tt.resources {
+				t.Fatalf("%d resources, want %d", got, tt.resources)
+			}
+
+			nodes, pods, podUIDs, containers := map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}
+			for _, rm := range file.ResourceMetrics {
+				r := rm.Resource
+				switch resourceLevel(r) {
+				case levelNode:
+					nodes[attribute(r, "k8s.node.name")+"/"+attribute(r, "k8s.node.uid")] = true
+				case levelPod:
+					pods[attribute(r, "k8s.node.name")+"/"+attribute(r, "k8s.pod.name")] = true
+					podUIDs[attribute(r, "k8s.pod.uid")] = true
+				case levelContainer:
+					containers[attribute(r, "container.id")] = true
+				}
+			}
+			for _, c := range []struct {
+				level string
+				got   int
+				want  int
+			}{
+				{"nodes", len(nodes), tt.nodes},
+				{"pods", len(pods), tt.pods},
+				{"pod uids", len(podUIDs), tt.podUIDs},
+				{"containers", len(containers), tt.containers},
+			} {
+				if c.got != c.want {
+					t.Errorf("%d distinct %s, want %d", c.got, c.level, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeriveIDKeepsLayout(t *testing.T) {
+	for _, tt := range []struct {
+		original string
+		layout   string
+	}{
+		{"7f8e3d2a-1b4c-4d5e-8f9a-0b1c2d3e4f5a", `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`},
+		{"containerd://0123456789abcdef", `^containerd://[0-9a-f]{16}$`},
+		{"i-0abc", `^[0-9a-f]-[0-9a-f]{4}$`},
+	} {
+		first, second := deriveID(tt.original, 0), deriveID(tt.original, 1)
+		for _, id := range []string{first, second} {
+			if !regexp.MustCompile(tt.layout).MatchString(id) {
+				t.Errorf("deriveID(%q) = %q, want the layout %s", tt.original, id, tt.layout)
+			}
+		}
+		if first == second || first != deriveID(tt.original, 0) {
+			t.Errorf("deriveID(%q): copies %q and %q must differ and be stable", tt.original, first, second)
+		}
+	}
+}
@@ -2,14 +2,16 @@ package common
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
-
-	"gopkg.in/yaml.v3"
+	"time"
 )
 
 // configStruct defines how config.yaml is parsed
 type configStruct struct {
+	Version int `yaml:"version"` // Schema version, see ConfigVersion
+
 	BaseCluster  string `yaml:"base_cluster"`
 	BaseName     string `yaml:"base_name"`
 	NoReplicas   int    `yaml:"no_replicas"`
@@ -17,49 +19,269 @@ type configStruct struct {
 	InputDir     string `yaml:"input_dir"`
 	DebugDir     string `yaml:"debug_dir"`
 	InputFile    string `yaml:"input_file"`
+	TracesFile   string `yaml:"traces_file"`
+	LogsFile     string `yaml:"logs_file"`
+
+	Protocol     string          `yaml:"protocol"`
+	Compression  string          `yaml:"compression"`
+	GRPCEndpoint string          `yaml:"grpc_endpoint"`
+	GRPCInsecure bool            `yaml:"grpc_insecure"`
+	GRPCCAFile   string          `yaml:"grpc_ca_file"`
+	Endpoints    []string        `yaml:"endpoints"`
+	Balancing    BalancingConfig `yaml:"balancing"`
+	Headers      []HeaderConfig  `yaml:"headers"`
+	Auth         AuthConfig      `yaml:"auth"`
+	TLS          TLSConfig       `yaml:"tls"`
+	Timeout      time.Duration   `yaml:"timeout"`
+	Retry        RetryPolicy     `yaml:"retry"`
+	Workers      int             `yaml:"workers"`
+	QueueSize    int             `yaml:"queue_size"`
+	Interval     time.Duration   `yaml:"interval"`
+	LogsPerSec   int             `yaml:"logs_per_second"`
+	LogsBatch    int             `yaml:"logs_batch_size"`
+	LoadProfile  LoadProfile     `yaml:"load_profile"`
+
+	ValueGenerators []ValueGenerator `yaml:"value_generators"`
+	Counters        []CounterRule    `yaml:"counters"`
+	Histograms      []HistogramRule  `yaml:"histograms"`
+	Cardinality     Cardinality      `yaml:"cardinality"`
+	RewriteRules    []RewriteRule    `yaml:"rewrite_rules"`
+	Sinks           []SinkConfig     `yaml:"sinks"`
+	Report          ReportConfig     `yaml:"report"`
+	Telemetry       TelemetryConfig  `yaml:"telemetry"`
 }
 
-var replicasOverride int
+var (
+	replicasOverride int
+	validateOnly     bool
+	baseConfig       configStruct // As read at startup, the defaults of every scenario phase
+	loadedConfig     configStruct // As last applied, compared against on reload
+)
 
 // RegisterFlags allows other files to use --replicas
 func RegisterFlags() {
 	flag.IntVar(&replicasOverride, "replicas", 1, "Override the number of replicas in config.yaml")
 }
 
-// LoadConfig reads config.yaml, applies overrides, and validates fields
-func LoadConfig(path string) {
+// RegisterValidateFlag adds -validate, which checks the config file and exits
+func RegisterValidateFlag() {
+	flag.BoolVar(&validateOnly, "validate", false, "Check the config file, report every problem and exit")
+}
+
+// LoadConfig reads config.yaml for the binary whose section is given, applies overrides,
+// and validates fields. Every problem in the file is reported at once on stderr before
+// exiting; with -validate it exits after the check either way.
+func LoadConfig(path string, section string) {
+	cfg, problems := readConfig(path, section)
+	problems.exitIfAny(path)
+	if validateOnly {
+		fmt.Printf("✅ %s is valid for %s (config version %d)\n", path, section, cfg.Version)
+		os.Exit(0)
+	}
+	if replicasOverride > 1 {
+		log.Printf("⚙️ Overriding replicas from CLI: %d", cfg.NoReplicas)
+	}
+	baseConfig = cfg
+	applyConfig(cfg)
+}
+
+// readConfig parses and validates a config file without touching the globals
+func readConfig(path string, section string) (configStruct, configProblems) {
+	cfg := configStruct{
+		Protocol:     ProtocolHTTPJSON,
+		Compression:  CompressionNone,
+		GRPCEndpoint: "localhost:4317",
+		GRPCInsecure: true,
+		Balancing:    BalancingConfig{Strategy: BalanceRoundRobin, HashAttribute: "k8s.cluster.name"},
+		Timeout:      10 * time.Second,
+		Retry:        DefaultRetryPolicy(),
+		NoReplicas:   1,
+		Workers:      4,
+		QueueSize:    64,
+		Interval:     10 * time.Second,
+		LogsPerSec:   100,
+		LogsBatch:    100,
+		Report:       ReportConfig{Interval: time.Minute, Format: ReportText},
+		Telemetry:    TelemetryConfig{PushInterval: 15 * time.Second},
+	}
+	var problems configProblems
+
 	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatalf("❌ Failed to read config file: %v", err)
+		problems.add("failed to read config file: %v", err)
+		return cfg, problems
 	}
+	parseConfig(data, section, &cfg, &problems)
 
-	var cfg configStruct
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		log.Fatalf("❌ Failed to parse config file: %v", err)
+	if len(cfg.Sinks) == 0 {
+		cfg.Sinks = []SinkConfig{{Type: SinkOTLP}}
+	}
+	if len(cfg.RewriteRules) == 0 {
+		cfg.RewriteRules = DefaultRewriteRules()
+	}
+	if replicasOverride > 1 {
+		cfg.NoReplicas = replicasOverride
+	}
+	for _, file := range []*string{&cfg.InputFile, &cfg.TracesFile, &cfg.LogsFile} {
+		if expanded, err := ExpandPath(*file); err == nil {
+			*file = expanded
+		}
 	}
 
-	BaseClusterName = cfg.BaseCluster
-	BaseNodeName = cfg.BaseName
-	CollectorURL = cfg.CollectorURL
-	InputDir = cfg.InputDir
-	DebugDir = cfg.DebugDir
+	switch cfg.Protocol {
+	case ProtocolGRPC, ProtocolHTTPJSON, ProtocolHTTPProtobuf:
+	default:
+		problems.add("invalid protocol: %q (must be %s, %s or %s)", cfg.Protocol, ProtocolGRPC, ProtocolHTTPJSON, ProtocolHTTPProtobuf)
+	}
 
-	if replicasOverride > 1 {
-		NoReplicas = replicasOverride
-		log.Printf("⚙️ Overriding replicas from CLI: %d", NoReplicas)
-	} else {
-		NoReplicas = cfg.NoReplicas
+	switch cfg.Compression {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		problems.add("invalid compression: %q (must be %s, %s or %s)", cfg.Compression, CompressionNone, CompressionGzip, CompressionZstd)
+	}
+
+	for i, endpoint := range cfg.Endpoints {
+		if endpoint == "" {
+			problems.add("invalid endpoints[%d]: empty endpoint", i)
+		}
+	}
+
+	for i, header := range cfg.Headers {
+		if err := header.Validate(); err != nil {
+			problems.add("invalid headers[%d]: %v", i, err)
+		}
+	}
+
+	if err := cfg.Auth.Validate(); err != nil {
+		problems.add("invalid auth: %v", err)
+	}
+
+	if err := cfg.TLS.Validate(); err != nil {
+		problems.add("invalid tls settings: %v", err)
+	}
+
+	if err := cfg.Balancing.Validate(); err != nil {
+		problems.add("invalid balancing: %v", err)
+	}
+
+	for i, sink := range cfg.Sinks {
+		if err := sink.Validate(); err != nil {
+			problems.add("invalid sinks[%d]: %v", i, err)
+		}
+	}
+
+	if err := cfg.Report.Validate(); err != nil {
+		problems.add("invalid report settings: %v", err)
+	}
+
+	if err := cfg.Telemetry.Validate(); err != nil {
+		problems.add("invalid telemetry settings: %v", err)
+	}
+
+	if cfg.Timeout <= 0 {
+		problems.add("invalid timeout: %s (must be > 0)", cfg.Timeout)
+	}
+
+	if cfg.Workers <= 0 {
+		problems.add("invalid number of workers: %d (must be > 0)", cfg.Workers)
+	}
+
+	if cfg.QueueSize < 0 {
+		problems.add("invalid queue size: %d (must be >= 0)", cfg.QueueSize)
+	}
+
+	if cfg.Interval <= 0 {
+		problems.add("invalid interval: %s (must be > 0)", cfg.Interval)
+	}
+
+	if cfg.LogsPerSec <= 0 || cfg.LogsBatch <= 0 {
+		problems.add("invalid log rate: logs_per_second and logs_batch_size must be > 0, got %d and %d", cfg.LogsPerSec, cfg.LogsBatch)
+	}
+
+	if err := cfg.LoadProfile.Validate(); err != nil {
+		problems.add("invalid load profile: %v", err)
+	}
+
+	if err := cfg.Cardinality.Validate(); err != nil {
+		problems.add("invalid cardinality: %v", err)
+	}
+
+	for i := range cfg.RewriteRules {
+		if err := cfg.RewriteRules[i].Compile(); err != nil {
+			problems.add("invalid rewrite_rules[%d]: %v", i, err)
+		}
+	}
+
+	for i, gen := range cfg.ValueGenerators {
+		if err := gen.Validate(); err != nil {
+			problems.add("invalid value_generators[%d]: %v", i, err)
+		}
+	}
+
+	for i, rule := range cfg.Counters {
+		if err := rule.Validate(); err != nil {
+			problems.add("invalid counters[%d]: %v", i, err)
+		}
+	}
+
+	for i, rule := range cfg.Histograms {
+		if err := rule.Validate(); err != nil {
+			problems.add("invalid histograms[%d]: %v", i, err)
+		}
 	}
 
-	if NoReplicas <= 0 {
-		log.Fatalf("❌ Invalid number of replicas: %d (must be > 0)", NoReplicas)
+	if cfg.Retry.Enabled {
+		if err := cfg.Retry.Validate(); err != nil {
+			problems.add("invalid retry policy: %v", err)
+		}
 	}
 
-	if expanded, err := ExpandPath(cfg.InputFile); err == nil {
-		InputFile = expanded
-	} else {
-		InputFile = cfg.InputFile
+	// The load profile sets the replica count itself while it runs
+	if cfg.NoReplicas <= 0 && len(cfg.LoadProfile) == 0 {
+		problems.add("invalid number of replicas: %d (must be > 0)", cfg.NoReplicas)
 	}
+
+	return cfg, problems
+}
+
+// applyConfig sets the globals from a validated config
+func applyConfig(cfg configStruct) {
+	loadedConfig = cfg
+	BaseClusterName = cfg.BaseCluster
+	BaseNodeName = cfg.BaseName
+	NoReplicas = cfg.NoReplicas
+	CollectorURL = cfg.CollectorURL
+	InputDir = cfg.InputDir
+	DebugDir = cfg.DebugDir
+	InputFile = cfg.InputFile
+	TracesFile = cfg.TracesFile
+	LogsFile = cfg.LogsFile
+	Protocol = cfg.Protocol
+	Compression = cfg.Compression
+	GRPCEndpoint = cfg.GRPCEndpoint
+	GRPCInsecure = cfg.GRPCInsecure
+	GRPCCAFile = cfg.GRPCCAFile
+	Endpoints = cfg.Endpoints
+	Balancing = cfg.Balancing
+	Headers = cfg.Headers
+	Auth = cfg.Auth
+	TLS = cfg.TLS
+	ExportTimeout = cfg.Timeout
+	Retry = cfg.Retry
+	Workers = cfg.Workers
+	QueueSize = cfg.QueueSize
+	Interval = cfg.Interval
+	LogsPerSecond = cfg.LogsPerSec
+	LogsBatchSize = cfg.LogsBatch
+	Profile = cfg.LoadProfile
+	ValueGenerators = cfg.ValueGenerators
+	Counters = cfg.Counters
+	Histograms = cfg.Histograms
+	ResourceCardinality = cfg.Cardinality
+	RewriteRules = cfg.RewriteRules
+	Sinks = cfg.Sinks
+	ReportSettings = cfg.Report
+	TelemetrySettings = cfg.Telemetry
 }
 
 // Print selected fields from config for debug/info output
@@ -77,10 +299,79 @@ func PrintConfig(fields ...string) {
 			log.Printf("  InputDir:        %s", InputDir)
 		case "InputFile":
 			log.Printf("  InputFile:       %s", InputFile)
+		case "TracesFile":
+			log.Printf("  TracesFile:      %s", TracesFile)
+		case "LogsFile":
+			log.Printf("  LogsFile:        %s (%d records/s, batches of %d)", LogsFile, LogsPerSecond, LogsBatchSize)
 		case "DebugDir":
 			log.Printf("  DebugDir:        %s", DebugDir)
 		case "CollectorURL":
 			log.Printf("  CollectorURL:    %s", CollectorURL)
+		case "Endpoints":
+			for _, endpoint := range Endpoints {
+				log.Printf("  Endpoint:        %s (%s)", endpoint, Balancing.Strategy)
+			}
+		case "Sinks":
+			for _, sink := range Sinks {
+				if sink.Type == SinkFile {
+					log.Printf("  Sink:            %s %s (max %d MB, %d files)", sink.Type, sink.Path, sink.MaxSizeMB, sink.MaxFiles)
+				} else {
+					log.Printf("  Sink:            %s", sink.Type)
+				}
+			}
+		case "Telemetry":
+			if !TelemetrySettings.Enabled() {
+				log.Printf("  Telemetry:       off")
+			}
+			if TelemetrySettings.Listen != "" {
+				log.Printf("  Telemetry:       http://%s/metrics", TelemetrySettings.Listen)
+			}
+			if TelemetrySettings.OTLPEndpoint != "" {
+				log.Printf("  Telemetry:       push to %s every %s", TelemetrySettings.OTLPEndpoint, TelemetrySettings.PushInterval)
+			}
+		case "Protocol":
+			log.Printf("  Protocol:        %s", Protocol)
+		case "Compression":
+			log.Printf("  Compression:     %s", Compression)
+		case "GRPCEndpoint":
+			log.Printf("  GRPCEndpoint:    %s (insecure: %t)", GRPCEndpoint, GRPCInsecure)
+		case "ExportTimeout":
+			log.Printf("  ExportTimeout:   %s", ExportTimeout)
+		case "Workers":
+			log.Printf("  Workers:         %d (queue size %d)", Workers, QueueSize)
+		case "Interval":
+			log.Printf("  Interval:        %s", Interval)
+		case "LoadProfile":
+			if len(Profile) == 0 {
+				log.Printf("  LoadProfile:     none (constant %d replicas)", NoReplicas)
+			} else {
+				log.Printf("  LoadProfile:     %d stages, %s total", len(Profile), Profile.Total())
+			}
+		case "Cardinality":
+			log.Printf("  Cardinality:     %d nodes/cluster, %d pods/node, %d containers/pod",
+				max(ResourceCardinality.NodesPerCluster, 1), max(ResourceCardinality.PodsPerNode, 1), max(ResourceCardinality.ContainersPerPod, 1))
+		case "RewriteRules":
+			for _, rule := range RewriteRules {
+				key := rule.Key
+				if rule.KeyRegex != "" {
+					key = "/" + rule.KeyRegex + "/"
+				}
+				log.Printf("  RewriteRule:     %s %s -> %s", rule.Level, key, rule.Value)
+			}
+		case "ValueGenerators":
+			for _, gen := range ValueGenerators {
+				log.Printf("  ValueGenerator:  %s -> %s", gen.Match, gen.Type)
+			}
+		case "Counters":
+			for _, rule := range Counters {
+				log.Printf("  Counter:         %s +%g/interval (reset every %s)", rule.Match, rule.Increment, rule.ResetEvery)
+			}
+		case "Histograms":
+			for _, rule := range Histograms {
+				log.Printf("  Histogram:       %s -> %s", rule.Match, rule.Distribution)
+			}
+		case "Retry":
+			log.Printf("  Retry:           %+v", Retry)
 		default:
 			log.Printf("  ⚠️ Unknown config field: %s", field)
 		}
@@ -0,0 +1,265 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigVersion is the config.yaml schema version this build understands. A file without a
+// version key is read as this version.
+const ConfigVersion = 1
+
+// ConfigSections are the per-binary sections of config.yaml. The keys of a binary's own
+// section override the top-level keys of the same name for that binary only.
+var ConfigSections = []string{"extract_metrics", "metrics_loadgen", "node_loadgen", "traces_loadgen", "logs_loadgen"}
+
+// configProblems collects every problem found in a config file, so all of them can be
+// reported at once instead of one per run
+type configProblems []string
+
+func (p *configProblems) add(format string, args ...any) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+// exitIfAny prints every problem found in the file at path on stderr and exits
+func (p configProblems) exitIfAny(path string) {
+	if len(p) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "❌ %s has %d problem(s):\n", path, len(p))
+	for _, problem := range p {
+		fmt.Fprintf(os.Stderr, "  - %s\n", problem)
+	}
+	os.Exit(1)
+}
+
+// addDecodeProblems adds every type error of a failed decode, one problem per field
+func addDecodeProblems(err error, problems *configProblems) {
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		for _, msg := range typeErr.Errors {
+			problems.add("%s", msg)
+		}
+		return
+	}
+	problems.add("%v", err)
+}
+
+// envPattern matches ${VAR} and ${VAR:-default}
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// parseConfig decodes a config file for the given section into cfg, which holds the
+// defaults. Unknown keys, unset environment variables and type errors are all collected.
+func parseConfig(data []byte, section string, cfg *configStruct, problems *configProblems) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		problems.add("%v", err)
+		return
+	}
+	if len(doc.Content) == 0 {
+		problems.add("the file is empty")
+		return
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		problems.add("line %d: the file must be a mapping of keys to values", root.Line)
+		return
+	}
+
+	configType := reflect.TypeOf(configStruct{})
+	checkKnownKeys(root, configType, "", problems)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if isConfigSection(key.Value) {
+			if value.Kind != yaml.MappingNode {
+				problems.add("line %d: section %s must be a mapping", value.Line, key.Value)
+				continue
+			}
+			checkKnownKeys(value, configType, key.Value, problems)
+		}
+	}
+	// Expand only what this binary reads, so a variable used by another section, or by a
+	// key the section overrides, does not have to be set
+	merged := mergeSection(root, section)
+	expandEnv(merged, problems)
+	if err := merged.Decode(cfg); err != nil {
+		addDecodeProblems(err, problems)
+	}
+
+	if cfg.Version == 0 {
+		cfg.Version = ConfigVersion
+	}
+	if cfg.Version != ConfigVersion {
+		problems.add("unsupported config version %d (this build reads version %d)", cfg.Version, ConfigVersion)
+	}
+}
+
+func isConfigSection(key string) bool {
+	for _, section := range ConfigSections {
+		if key == section {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeSection returns the top-level mapping without any section, with the keys of the
+// given section laid over it
+func mergeSection(root *yaml.Node, section string) *yaml.Node {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	var overrides *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i].Value
+		if key == section {
+			overrides = root.Content[i+1]
+		}
+		if !isConfigSection(key) {
+			merged.Content = append(merged.Content, root.Content[i], root.Content[i+1])
+		}
+	}
+	if overrides == nil || overrides.Kind != yaml.MappingNode {
+		return merged
+	}
+	for i := 0; i+1 < len(overrides.Content); i += 2 {
+		key, value := overrides.Content[i], overrides.Content[i+1]
+		replaced := false
+		for j := 0; j+1 < len(merged.Content); j += 2 {
+			if merged.Content[j].Value == key.Value {
+				merged.Content[j+1] = value
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+	return merged
+}
+
+// checkKnownKeys reports every mapping key below node that has no field in t. Sections are
+// checked separately, so they are accepted at the top level.
+func checkKnownKeys(node *yaml.Node, t reflect.Type, path string, problems *configProblems) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for i, item := range node.Content {
+			checkKnownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Map:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkKnownKeys(node.Content[i+1], t.Elem(), joinConfigPath(path, node.Content[i].Value), problems)
+		}
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if path == "" && isConfigSection(key.Value) {
+				continue
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				problems.add("line %d: unknown key %q%s%s", key.Line, key.Value, inConfigPath(path), suggestKey(key.Value, fields))
+				continue
+			}
+			checkKnownKeys(node.Content[i+1], field.Type, joinConfigPath(path, key.Value), problems)
+		}
+	}
+}
+
+// yamlFields maps the yaml keys of a struct to its fields
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if options == "inline" {
+			for inlined, inlinedField := range yamlFields(field.Type) {
+				fields[inlined] = inlinedField
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// suggestKey points at a known key containing the unknown one, e.g. no_replicas for replicas
+func suggestKey(key string, fields map[string]reflect.StructField) string {
+	if len(key) < 4 {
+		return ""
+	}
+	var candidates []string
+	for known := range fields {
+		if strings.Contains(known, key) {
+			candidates = append(candidates, known)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.Strings(candidates)
+	return fmt.Sprintf(" (did you mean %q?)", candidates[0])
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func inConfigPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " in " + path
+}
+
+// expandEnv replaces ${VAR} and ${VAR:-default} in every scalar value. An unset variable
+// without a default is a problem rather than an empty string.
+func expandEnv(node *yaml.Node, problems *configProblems) {
+	if node.Kind == yaml.MappingNode {
+		// Keys are never expanded
+		for i := 1; i < len(node.Content); i += 2 {
+			expandEnv(node.Content[i], problems)
+		}
+		return
+	}
+	for _, child := range node.Content {
+		expandEnv(child, problems)
+	}
+	if node.Kind != yaml.ScalarNode || !strings.Contains(node.Value, "${") {
+		return
+	}
+	node.Value = envPattern.ReplaceAllStringFunc(node.Value, func(ref string) string {
+		match := envPattern.FindStringSubmatch(ref)
+		if value, ok := os.LookupEnv(match[1]); ok {
+			return value
+		}
+		if match[2] != "" {
+			return match[3]
+		}
+		problems.add("line %d: environment variable %s is not set", node.Line, match[1])
+		return ""
+	})
+	// Let the substituted value resolve to a number, bool or duration like a literal would
+	if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) == 0 {
+		node.Tag = ""
+	}
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func parseTestConfig(t *testing.T, data string, section string) (configStruct, configProblems) {
+	t.Helper()
+	cfg := configStruct{Interval: 10 * time.Second}
+	var problems configProblems
+	parseConfig([]byte(data), section, &cfg, &problems)
+	return cfg, problems
+}
+
+func TestMergeSection(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(`
+no_replicas: 2
+workers: 4
+metrics_loadgen:
+  no_replicas: 8
+  queue_size: 16
+node_loadgen:
+  workers: 1
+`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var merged struct {
+		NoReplicas int `yaml:"no_replicas"`
+		Workers    int `yaml:"workers"`
+		QueueSize  int `yaml:"queue_size"`
+	}
+	if err := mergeSection(doc.Content[0], "metrics_loadgen").Decode(&merged); err != nil {
+		t.Fatal(err)
+	}
+	if merged.NoReplicas != 8 || merged.Workers != 4 || merged.QueueSize != 16 {
+		t.Errorf("merged = %+v, want the section's no_replicas and queue_size over the top-level workers", merged)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("LG_TEST_REPLICAS", "7")
+	t.Setenv("LG_TEST_URL", "http://collector:4318")
+	cfg, problems := parseTestConfig(t, `
+no_replicas: ${LG_TEST_REPLICAS}
+collectorURL: "${LG_TEST_URL}"
+interval: ${LG_TEST_UNSET_INTERVAL:-30s}
+base_cluster: prefix-${LG_TEST_REPLICAS}-${LG_TEST_UNSET_SUFFIX:-}
+`, "metrics_loadgen")
+
+	if len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	if cfg.NoReplicas != 7 {
+		t.Errorf("no_replicas = %d, want 7 decoded as a number", cfg.NoReplicas)
+	}
+	if cfg.CollectorURL != "http://collector:4318" {
+		t.Errorf("collectorURL = %q", cfg.CollectorURL)
+	}
+	if cfg.Interval != 30*time.Second {
+		t.Errorf("interval = %s, want the 30s default", cfg.Interval)
+	}
+	if cfg.BaseCluster != "prefix-7-" {
+		t.Errorf("base_cluster = %q, want prefix-7-", cfg.BaseCluster)
+	}
+}
+
+func TestExpandEnvReportsUnsetVariables(t *testing.T) {
+	_, problems := parseTestConfig(t, "collectorURL: ${LG_TEST_UNSET_URL}\n", "metrics_loadgen")
+	if len(problems) != 1 || !strings.Contains(problems[0], "LG_TEST_UNSET_URL is not set") {
+		t.Errorf("problems = %v, want LG_TEST_UNSET_URL reported", problems)
+	}
+}
+
+func TestExpandEnvOnlyInOwnSection(t *testing.T) {
+	data := `
+collectorURL: ${LG_TEST_UNSET_TOP}
+metrics_loadgen:
+  collectorURL: http://localhost:4318
+node_loadgen:
+  grpc_endpoint: ${LG_TEST_UNSET_NODE}
+`
+	if _, problems := parseTestConfig(t, data, "metrics_loadgen"); len(problems) > 0 {
+		t.Errorf("metrics_loadgen: unexpected problems %v", problems)
+	}
+	_, problems := parseTestConfig(t, data, "node_loadgen")
+	if len(problems) != 2 {
+		t.Errorf("node_loadgen: problems = %v, want both unset variables", problems)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	_, problems := parseTestConfig(t, `
+no_replica: 2
+metrics_loadgen:
+  wrokers: 2
+`, "metrics_loadgen")
+	if len(problems) != 2 {
+		t.Fatalf("problems = %v, want two unknown keys", problems)
+	}
+	for i, want := range []string{`"no_replica"`, `"wrokers" in metrics_loadgen`} {
+		if !strings.Contains(problems[i], want) {
+			t.Errorf("problem %q does not mention %s", problems[i], want)
+		}
+	}
+}
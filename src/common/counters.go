@@ -0,0 +1,135 @@
+package common
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CounterRule is one entry of the counters list in config.yaml. It applies to every
+// cumulative monotonic sum whose metric name matches the Match glob.
+type CounterRule struct {
+	Match      string        `yaml:"match"`
+	Increment  float64       `yaml:"increment"`   // Added per interval, scaled when a load profile changes the interval
+	Jitter     float64       `yaml:"jitter"`      // +/- fraction applied to every increment
+	ResetEvery time.Duration `yaml:"reset_every"` // Simulates a restart: back to zero with a new start time (0 = never)
+}
+
+// Validate checks the pattern and the increment settings
+func (r CounterRule) Validate() error {
+	if _, err := path.Match(r.Match, ""); err != nil || r.Match == "" {
+		return fmt.Errorf("invalid match pattern %q", r.Match)
+	}
+	if r.Increment < 0 {
+		return fmt.Errorf("%s: increment must be >= 0, got %g", r.Match, r.Increment)
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return fmt.Errorf("%s: jitter must be between 0 and 1, got %g", r.Match, r.Jitter)
+	}
+	if r.ResetEvery < 0 {
+		return fmt.Errorf("%s: reset_every must be >= 0, got %s", r.Match, r.ResetEvery)
+	}
+	return nil
+}
+
+// counterState is the running value of one simulated counter series. Its age is the sum
+// of the intervals it was sent with, so resets do not depend on scheduling delays.
+type counterState struct {
+	value     float64
+	startTime int64
+	age       time.Duration
+}
+
+// CounterTracker keeps cumulative counters growing across iterations instead of resending
+// the captured value. It must be shared across iterations.
+type CounterTracker struct {
+	rules    []CounterRule
+	interval time.Duration
+	mu       sync.Mutex
+	series   map[seriesKey]*counterState
+}
+
+func NewCounterTracker(rules []CounterRule, interval time.Duration) *CounterTracker {
+	return &CounterTracker{rules: rules, interval: interval, series: make(map[seriesKey]*counterState)}
+}
+
+func (t *CounterTracker) ruleFor(name string) *CounterRule {
+	for i := range t.rules {
+		if ok, _ := path.Match(t.rules[i].Match, name); ok {
+			return &t.rules[i]
+		}
+	}
+	return nil
+}
+
+// Apply advances the counters of one replica's copy of the capture by one iteration of
+// the given interval. Call it after the timestamps were refreshed, since it sets the start
+// time of every tracked series.
+func (t *CounterTracker) Apply(metricsFile *MetricsFile, replica int, now int64, interval time.Duration) {
+	if t == nil || len(t.rules) == 0 {
+		return
+	}
+	for r := range metricsFile.ResourceMetrics {
+		for s := range metricsFile.ResourceMetrics[r].ScopeMetrics {
+			metrics := metricsFile.ResourceMetrics[r].ScopeMetrics[s].Metrics
+			for i := range metrics {
+				sum := metrics[i].Sum
+				if sum == nil || sum.AggregationTemporality != 2 || !sum.IsMonotonic {
+					continue
+				}
+				rule := t.ruleFor(metrics[i].Name)
+				if rule == nil {
+					continue
+				}
+				for p := range sum.DataPoints {
+					t.advance(rule, &sum.DataPoints[p], seriesKey{replica, r, s, i, p}, now, interval)
+				}
+			}
+		}
+	}
+}
+
+func (t *CounterTracker) advance(rule *CounterRule, dp *DataPoint, key seriesKey, now int64, interval time.Duration) {
+	captured, isInt, ok := dataPointValue(dp)
+	if !ok {
+		return
+	}
+
+	end, err := strconv.ParseInt(dp.TimeUnixNano, 10, 64)
+	if err != nil {
+		end = now
+		dp.TimeUnixNano = strconv.FormatInt(now, 10)
+	}
+
+	t.mu.Lock()
+	state, seen := t.series[key]
+	switch {
+	case !seen:
+		// The series starts with this run, from the captured value, one interval before
+		// the point so its window is never empty
+		state = &counterState{value: captured, startTime: seriesStart(end, interval)}
+		t.series[key] = state
+	case rule.ResetEvery > 0 && state.age+interval >= rule.ResetEvery:
+		state.value, state.startTime, state.age = 0, seriesStart(end, interval), 0
+	default:
+		increment := rule.Increment * float64(interval) / float64(t.interval)
+		if rule.Jitter > 0 {
+			increment *= 1 + rule.Jitter*(2*ReplicaRand(key.replica).Float64()-1)
+		}
+		state.value += increment
+		state.age += interval
+	}
+	value, startTime := state.value, state.startTime
+	t.mu.Unlock()
+
+	setDataPointValue(dp, value, isInt)
+	dp.StartTimeUnixNano = strconv.FormatInt(startTime, 10)
+}
+
+// seriesStart is the start time of a cumulative series that begins with the point ending
+// at end: the interval before it, as if the series had been sampled once already
+func seriesStart(end int64, interval time.Duration) int64 {
+	return end - int64(interval)
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func counterFile(value float64, end int64) *MetricsFile {
+	return &MetricsFile{ResourceMetrics: []ResourceMetric{{
+		ScopeMetrics: []ScopeMetric{{
+			Metrics: []Metric{{Name: "k8s.node.network.io", Sum: &Sum{
+				AggregationTemporality: 2,
+				IsMonotonic:            true,
+				DataPoints:             []DataPoint{{AsDouble: &value, TimeUnixNano: strconv.FormatInt(end, 10)}},
+			}}},
+		}},
+	}}}
+}
+
+func TestCounterWindows(t *testing.T) {
+	const interval = 10 * time.Second
+	tracker := NewCounterTracker([]CounterRule{{Match: "k8s.node.*", Increment: 5, ResetEvery: 30 * time.Second}}, interval)
+
+	base := time.Now().UnixNano()
+	for i, want := range []struct {
+		value float64
+		start int64 // Relative to base
+	}{
+		{100, -int64(interval)}, // First point: the captured value over the interval before it
+		{105, -int64(interval)},
+		{110, -int64(interval)},
+		{0, 2 * int64(interval)}, // Reset: a new start one interval before this point
+		{5, 2 * int64(interval)},
+	} {
+		end := base + int64(i)*int64(interval)
+		file := counterFile(100, end)
+		// Apply runs a moment after UpdateTimestamps wrote the point's end time
+		tracker.Apply(file, 0, end+int64(time.Millisecond), interval)
+
+		dp := file.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Sum.DataPoints[0]
+		start, _ := strconv.ParseInt(dp.StartTimeUnixNano, 10, 64)
+		if dp.TimeUnixNano != strconv.FormatInt(end, 10) {
+			t.Errorf("iteration %d: end moved from %d to %s", i, end, dp.TimeUnixNano)
+		}
+		if start >= end {
+			t.Errorf("iteration %d: start %d is not before end %d", i, start, end)
+		}
+		if start != base+want.start || *dp.AsDouble != want.value {
+			t.Errorf("iteration %d: value %g from %d, want %g from %d", i, *dp.AsDouble, start-base, want.value, want.start)
+		}
+	}
+}
@@ -7,11 +7,26 @@ import (
 
 // DeepCopyMetricsFile returns a full deep copy of the given MetricsFile
 func DeepCopyMetricsFile(orig MetricsFile) MetricsFile {
-	var copy MetricsFile
+	return deepCopyJSON(orig)
+}
+
+// DeepCopyTraceFile returns a full deep copy of the given TraceFile
+func DeepCopyTraceFile(orig TraceFile) TraceFile {
+	return deepCopyJSON(orig)
+}
+
+// DeepCopyLogFile returns a full deep copy of the given LogFile
+func DeepCopyLogFile(orig LogFile) LogFile {
+	return deepCopyJSON(orig)
+}
+
+// deepCopyJSON copies any of the OTLP file structs by round-tripping them through JSON
+func deepCopyJSON[T any](orig T) T {
+	var copy T
 
 	data, err := json.Marshal(orig)
 	if err != nil {
-		log.Printf("❌ Failed to marshal metrics for deep copy: %v", err)
+		log.Printf("❌ Failed to marshal for deep copy: %v", err)
 		return copy
 	}
 
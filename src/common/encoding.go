@@ -0,0 +1,206 @@
+package common
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Supported values for the compression setting in config.yaml
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// MarshalOTLP encodes an OTLP request for the given protocol and returns the body and its content type
+func MarshalOTLP(msg proto.Message, protocol string) ([]byte, string, error) {
+	if protocol == ProtocolHTTPProtobuf {
+		body, err := proto.Marshal(msg)
+		return body, "application/x-protobuf", err
+	}
+	// OTLP/JSON requires enums as integers, protojson defaults to their names
+	body, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(msg)
+	if err != nil {
+		return nil, "", err
+	}
+	body, err = hexEncodeIDs(body)
+	return body, "application/json", err
+}
+
+// otlpIDFields are the bytes fields that OTLP/JSON encodes as hex instead of base64
+var otlpIDFields = map[string]bool{"traceId": true, "spanId": true, "parentSpanId": true}
+
+// hexEncodeIDs rewrites the base64 trace and span IDs produced by protojson into the
+// lowercase hex the OTLP/JSON spec (and the collector) expects
+func hexEncodeIDs(body []byte) ([]byte, error) {
+	if !bytes.Contains(body, []byte(`"traceId"`)) && !bytes.Contains(body, []byte(`"spanId"`)) {
+		return body, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if err := convertIDFields(doc, func(v string) (string, error) {
+		raw, err := base64.StdEncoding.DecodeString(v)
+		return hex.EncodeToString(raw), err
+	}); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// convertIDFields applies convert to every ID field found anywhere in the decoded document
+func convertIDFields(node any, convert func(string) (string, error)) error {
+	switch n := node.(type) {
+	case map[string]any:
+		for key, value := range n {
+			if str, ok := value.(string); ok && otlpIDFields[key] {
+				converted, err := convert(str)
+				if err != nil {
+					return fmt.Errorf("invalid %s %q: %w", key, str, err)
+				}
+				n[key] = converted
+			} else if err := convertIDFields(value, convert); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, value := range n {
+			if err := convertIDFields(value, convert); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UnmarshalOTLP decodes an OTLP/HTTP request body based on its content type, accepting
+// the hex trace and span IDs of OTLP/JSON
+func UnmarshalOTLP(body []byte, contentType string, msg proto.Message) error {
+	if strings.Contains(contentType, "application/x-protobuf") {
+		return proto.Unmarshal(body, msg)
+	}
+	if bytes.Contains(body, []byte(`"traceId"`)) || bytes.Contains(body, []byte(`"spanId"`)) {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		var doc any
+		if err := dec.Decode(&doc); err != nil {
+			return err
+		}
+		if err := convertIDFields(doc, func(v string) (string, error) {
+			raw, err := hex.DecodeString(v)
+			return base64.StdEncoding.EncodeToString(raw), err
+		}); err != nil {
+			return err
+		}
+		var err error
+		if body, err = json.Marshal(doc); err != nil {
+			return err
+		}
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, msg)
+}
+
+// unmarshalOTLPResponse decodes a collector response body based on its content type
+func unmarshalOTLPResponse(body []byte, contentType string, msg proto.Message) error {
+	if strings.Contains(contentType, "application/x-protobuf") {
+		return proto.Unmarshal(body, msg)
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, msg)
+}
+
+// Compress applies the given Content-Encoding to body; "none" or "" returns body unchanged
+func Compress(body []byte, compression string) ([]byte, error) {
+	switch compression {
+	case CompressionNone, "":
+		return body, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		if _, err := gw.Write(body); err != nil {
+			return nil, fmt.Errorf("gzip write failed: %w", err)
+		}
+		if err := gw.Close(); err != nil {
+			return nil, fmt.Errorf("gzip close failed: %w", err)
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		return zstdEncoder().EncodeAll(body, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+}
+
+// Decompress reverses the given Content-Encoding; "none", "identity" or "" returns body unchanged
+func Decompress(body []byte, compression string) ([]byte, error) {
+	switch compression {
+	case CompressionNone, "identity", "":
+		return body, nil
+	case CompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("gzip read failed: %w", err)
+		}
+		defer gr.Close()
+		return io.ReadAll(gr)
+	case CompressionZstd:
+		dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		return dec.DecodeAll(body, nil)
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+}
+
+var (
+	zstdOnce sync.Once
+	zstdEnc  *zstd.Encoder
+)
+
+// zstdEncoder returns a shared encoder; EncodeAll is safe for concurrent use
+func zstdEncoder() *zstd.Encoder {
+	zstdOnce.Do(func() {
+		zstdEnc, _ = zstd.NewWriter(nil)
+	})
+	return zstdEnc
+}
+
+// zstdCompressor registers zstd as a gRPC compressor; grpc-go only ships gzip
+type zstdCompressor struct{}
+
+func init() {
+	encoding.RegisterCompressor(zstdCompressor{})
+}
+
+func (zstdCompressor) Name() string { return CompressionZstd }
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+}
+
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	// Concurrency 1 decodes synchronously, so no goroutines outlive the message
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return dec.IOReadCloser(), nil
+}
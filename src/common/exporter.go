@@ -0,0 +1,102 @@
+package common
+
+import (
+	"fmt"
+	"log"
+
+	logscollectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	tracecollectorpb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Supported values for the protocol setting in config.yaml
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPJSON     = "http/json"
+	ProtocolHTTPProtobuf = "http/protobuf"
+)
+
+// otlpSignal describes how export requests of one telemetry signal are routed
+type otlpSignal struct {
+	name        string // Used in log lines
+	path        string // OTLP/HTTP path appended to the collector URL
+	newResponse func() proto.Message
+}
+
+// signalOf maps an export request to its signal
+func signalOf(req proto.Message) (otlpSignal, error) {
+	switch req.(type) {
+	case *collectorpb.ExportMetricsServiceRequest:
+		return otlpSignal{"metrics", "/v1/metrics", func() proto.Message { return &collectorpb.ExportMetricsServiceResponse{} }}, nil
+	case *tracecollectorpb.ExportTraceServiceRequest:
+		return otlpSignal{"traces", "/v1/traces", func() proto.Message { return &tracecollectorpb.ExportTraceServiceResponse{} }}, nil
+	case *logscollectorpb.ExportLogsServiceRequest:
+		return otlpSignal{"logs", "/v1/logs", func() proto.Message { return &logscollectorpb.ExportLogsServiceResponse{} }}, nil
+	default:
+		return otlpSignal{}, fmt.Errorf("unsupported OTLP request type %T", req)
+	}
+}
+
+// newOTLPSink creates one exporter per collector endpoint, selected by the protocol setting
+// in config.yaml and wrapped with the configured retry policy. Several endpoints are
+// balanced as configured; retries stay on the endpoint of the first attempt.
+func newOTLPSink() (Sink, error) {
+	settings, err := newSenderSettings()
+	if err != nil {
+		return nil, err
+	}
+	endpoints := collectorEndpoints()
+	var sinks []Sink
+	for _, endpoint := range endpoints {
+		exp, err := newOTLPExporter(endpoint, settings)
+		if err != nil {
+			for _, created := range sinks {
+				created.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, exp)
+	}
+	if len(sinks) == 1 {
+		return sinks[0], nil
+	}
+	log.Printf("⚖️ Balancing across %d endpoints (%s)", len(endpoints), Balancing.Strategy)
+	return newBalancer(sinks, endpoints, Balancing), nil
+}
+
+// collectorEndpoints returns the configured endpoints, or the single collector URL or gRPC
+// endpoint of the protocol
+func collectorEndpoints() []string {
+	if len(Endpoints) > 0 {
+		return Endpoints
+	}
+	if Protocol == ProtocolGRPC {
+		return []string{GRPCEndpoint}
+	}
+	return []string{CollectorURL}
+}
+
+func newOTLPExporter(endpoint string, settings senderSettings) (Sink, error) {
+	var (
+		exp Sink
+		err error
+	)
+	switch Protocol {
+	case ProtocolGRPC:
+		exp, err = newGRPCExporter(endpoint, GRPCInsecure, Compression, ExportTimeout, settings)
+	case ProtocolHTTPJSON, ProtocolHTTPProtobuf, "":
+		exp = newHTTPExporter(endpoint, Protocol, Compression, ExportTimeout, settings)
+	default:
+		err = fmt.Errorf("unsupported protocol %q", Protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if Retry.Enabled {
+		exp = newRetryingExporter(exp, Retry)
+	}
+	// Above the retries, so the report counts one request however many attempts it took
+	return &reportingSink{name: endpoint, next: exp}, nil
+}
@@ -0,0 +1,103 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	logscollectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	tracecollectorpb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/encoding/gzip" // registers the gzip compressor
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// grpcExporter sends OTLP requests through the Export RPC of the matching collector service
+type grpcExporter struct {
+	conn     *grpc.ClientConn
+	metrics  collectorpb.MetricsServiceClient
+	traces   tracecollectorpb.TraceServiceClient
+	logs     logscollectorpb.LogsServiceClient
+	endpoint string
+	timeout  time.Duration
+	metadata metadata.MD // The configured headers, sent with every call
+}
+
+func newGRPCExporter(endpoint string, plaintext bool, compression string, timeout time.Duration, settings senderSettings) (*grpcExporter, error) {
+	var creds credentials.TransportCredentials
+	if plaintext {
+		creds = insecure.NewCredentials()
+	} else {
+		creds = credentials.NewTLS(settings.tls)
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if compression != CompressionNone && compression != "" {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(compression)))
+	}
+
+	conn, err := grpc.NewClient(endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client for %s: %w", endpoint, err)
+	}
+
+	return &grpcExporter{
+		conn:     conn,
+		metrics:  collectorpb.NewMetricsServiceClient(conn),
+		traces:   tracecollectorpb.NewTraceServiceClient(conn),
+		logs:     logscollectorpb.NewLogsServiceClient(conn),
+		endpoint: endpoint,
+		timeout:  timeout,
+		metadata: metadata.New(settings.headers),
+	}, nil
+}
+
+func (e *grpcExporter) Export(ctx context.Context, otlpRequest proto.Message) error {
+	signal, err := signalOf(otlpRequest)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	if len(e.metadata) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, e.metadata)
+	}
+
+	Telemetry.requestStarted()
+	start := time.Now()
+	var resp proto.Message
+	switch req := otlpRequest.(type) {
+	case *collectorpb.ExportMetricsServiceRequest:
+		resp, err = e.metrics.Export(ctx, req)
+	case *tracecollectorpb.ExportTraceServiceRequest:
+		resp, err = e.traces.Export(ctx, req)
+	case *logscollectorpb.ExportLogsServiceRequest:
+		resp, err = e.logs.Export(ctx, req)
+	}
+	latency := time.Since(start)
+	Telemetry.requestDone()
+	// The size before compression; gRPC does not expose the compressed size per call
+	size := proto.Size(otlpRequest)
+	recordAttempt(ctx, size)
+	if err != nil {
+		exportErr := newGRPCExportError(err)
+		exportErr.Err = fmt.Errorf("failed to export OTLP %s via gRPC: %w", signal.name, err)
+		Telemetry.record(e.endpoint, otlpRequest, size, latency, exportErr)
+		return exportErr
+	}
+	Telemetry.record(e.endpoint, otlpRequest, size, latency, nil)
+	reportPartialSuccess(e.endpoint, resp)
+
+	log.Printf("✅ Successfully sent OTLP %s to %s (gRPC)", signal.name, e.endpoint)
+	return nil
+}
+
+func (e *grpcExporter) Close() error {
+	return e.conn.Close()
+}
@@ -0,0 +1,130 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// metricsServer answers every Export call with handle
+type metricsServer struct {
+	collectorpb.UnimplementedMetricsServiceServer
+	handle func(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error)
+}
+
+func (s *metricsServer) Export(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	return s.handle(ctx, req)
+}
+
+// startMetricsServer serves srv on a loopback port and returns an exporter sending to it
+func startMetricsServer(t *testing.T, srv *metricsServer, timeout time.Duration, settings senderSettings) *grpcExporter {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	collectorpb.RegisterMetricsServiceServer(server, srv)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	exporter, err := newGRPCExporter(listener.Addr().String(), true, CompressionGzip, timeout, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { exporter.Close() })
+	return exporter
+}
+
+func TestGRPCExporterPlaintext(t *testing.T) {
+	var (
+		received *collectorpb.ExportMetricsServiceRequest
+		token    []string
+	)
+	srv := &metricsServer{handle: func(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+		received = req
+		md, _ := metadata.FromIncomingContext(ctx)
+		token = md.Get("x-sf-token")
+		return &collectorpb.ExportMetricsServiceResponse{}, nil
+	}}
+	exporter := startMetricsServer(t, srv, 5*time.Second, senderSettings{headers: map[string]string{"X-SF-Token": "secret"}})
+
+	if err := exporter.Export(context.Background(), testMetricsRequest("plaintext")); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if got := received.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()[0].GetName(); got != "plaintext" {
+		t.Errorf("server received metric %q, want plaintext", got)
+	}
+	if len(token) != 1 || token[0] != "secret" {
+		t.Errorf("x-sf-token metadata = %v, want [secret]", token)
+	}
+}
+
+func TestGRPCExporterDeadline(t *testing.T) {
+	srv := &metricsServer{handle: func(ctx context.Context, _ *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	exporter := startMetricsServer(t, srv, 50*time.Millisecond, senderSettings{})
+
+	start := time.Now()
+	err := exporter.Export(context.Background(), testMetricsRequest("slow"))
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("export took %s, the 50ms deadline was not applied", elapsed)
+	}
+	var exportErr *ExportError
+	if !errors.As(err, &exportErr) {
+		t.Fatalf("error %v is not an *ExportError", err)
+	}
+	if exportErr.GRPCCode != codes.DeadlineExceeded || !exportErr.Retryable {
+		t.Errorf("got code %s retryable %t, want DeadlineExceeded retryable", exportErr.GRPCCode, exportErr.Retryable)
+	}
+}
+
+func TestGRPCExporterErrorMapping(t *testing.T) {
+	throttled, err := status.New(codes.ResourceExhausted, "slow down").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(3 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		err        error
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{"unavailable", status.Error(codes.Unavailable, "down"), true, 0},
+		{"invalid argument", status.Error(codes.InvalidArgument, "bad"), false, 0},
+		{"unauthenticated", status.Error(codes.Unauthenticated, "who"), false, 0},
+		{"exhausted without retry info", status.Error(codes.ResourceExhausted, "full"), false, 0},
+		{"exhausted with retry info", throttled.Err(), true, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &metricsServer{handle: func(context.Context, *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+				return nil, tt.err
+			}}
+			exporter := startMetricsServer(t, srv, 5*time.Second, senderSettings{})
+
+			var exportErr *ExportError
+			if err := exporter.Export(context.Background(), testMetricsRequest("failing")); !errors.As(err, &exportErr) {
+				t.Fatalf("error %v is not an *ExportError", err)
+			}
+			if exportErr.GRPCCode != status.Code(tt.err) {
+				t.Errorf("code = %s, want %s", exportErr.GRPCCode, status.Code(tt.err))
+			}
+			if exportErr.Retryable != tt.retryable || exportErr.RetryAfter != tt.retryAfter {
+				t.Errorf("retryable %t after %s, want %t after %s", exportErr.Retryable, exportErr.RetryAfter, tt.retryable, tt.retryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// httpExporter posts OTLP requests to <CollectorURL>/v1/<signal> as JSON or binary protobuf
+type httpExporter struct {
+	client      *http.Client
+	baseURL     string
+	protocol    string
+	compression string
+	timeout     time.Duration
+	headers     map[string]string
+	untracked   bool // Not counted in the report and self-telemetry, e.g. the self-telemetry push
+}
+
+func newHTTPExporter(baseURL string, protocol string, compression string, timeout time.Duration, settings senderSettings) *httpExporter {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = settings.tls
+	return &httpExporter{
+		client:      &http.Client{Transport: transport},
+		baseURL:     baseURL,
+		protocol:    protocol,
+		compression: compression,
+		timeout:     timeout,
+		headers:     settings.headers,
+	}
+}
+
+func (e *httpExporter) Export(ctx context.Context, otlpRequest proto.Message) (exportErr error) {
+	signal, err := signalOf(otlpRequest)
+	if err != nil {
+		return err
+	}
+	url := e.baseURL + signal.path
+
+	body, contentType, err := MarshalOTLP(otlpRequest, e.protocol)
+	if err != nil {
+		return fmt.Errorf("failed to marshal OTLP request: %w", err)
+	}
+	rawSize := len(body)
+	if body, err = Compress(body, e.compression); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", contentType)
+	if e.compression != CompressionNone && e.compression != "" {
+		req.Header.Set("Content-Encoding", e.compression)
+	}
+
+	if !e.untracked {
+		Telemetry.requestStarted()
+		start := time.Now()
+		defer func() {
+			Telemetry.requestDone()
+			recordAttempt(ctx, len(body))
+			Telemetry.record(url, otlpRequest, len(body), time.Since(start), exportErr)
+		}()
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		// Connection failures and timeouts never reached the collector, so they are safe to retry
+		return &ExportError{Retryable: true, Err: fmt.Errorf("failed to send OTLP data: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &ExportError{
+			StatusCode: resp.StatusCode,
+			Retryable:  isRetryableHTTPStatus(resp.StatusCode),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			Err:        fmt.Errorf("unexpected response from OTLP receiver: %s - %s", resp.Status, string(respBody)),
+		}
+	}
+
+	if len(respBody) > 0 {
+		exportResp := signal.newResponse()
+		if err := unmarshalOTLPResponse(respBody, resp.Header.Get("Content-Type"), exportResp); err != nil {
+			log.Printf("⚠️ Could not parse collector response: %v", err)
+		} else {
+			reportPartialSuccess(url, exportResp)
+		}
+	}
+
+	if !e.untracked {
+		log.Printf("✅ Successfully sent OTLP %s to %s (status: %s, %d bytes, %d on the wire)", signal.name, url, resp.Status, rawSize, len(body))
+	}
+	return nil
+}
+
+func (e *httpExporter) Close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
@@ -1,14 +1,44 @@
 package common
 
+import "time"
+
 // Global configuration variables used across the app
 var (
-	BaseClusterName string
-	BaseNodeName    string
-	NoReplicas      int
-	InputDir        string
-	DebugDir        string
-	InputFile       string
-	CollectorURL    string
-	DebugEnabled    bool
-	InfoEnabled     bool
+	BaseClusterName     string
+	BaseNodeName        string
+	NoReplicas          int
+	InputDir            string
+	DebugDir            string
+	InputFile           string
+	TracesFile          string
+	LogsFile            string
+	LogsPerSecond       int
+	LogsBatchSize       int
+	CollectorURL        string
+	Protocol            string
+	Compression         string
+	GRPCEndpoint        string
+	Endpoints           []string
+	Balancing           BalancingConfig
+	Headers             []HeaderConfig
+	Auth                AuthConfig
+	TLS                 TLSConfig
+	GRPCInsecure        bool
+	GRPCCAFile          string
+	ExportTimeout       time.Duration
+	Retry               RetryPolicy
+	Workers             int
+	QueueSize           int
+	Interval            time.Duration
+	Profile             LoadProfile
+	ValueGenerators     []ValueGenerator
+	Counters            []CounterRule
+	Histograms          []HistogramRule
+	ResourceCardinality Cardinality
+	RewriteRules        []RewriteRule
+	Sinks               []SinkConfig
+	ReportSettings      ReportConfig
+	TelemetrySettings   TelemetryConfig
+	DebugEnabled        bool
+	InfoEnabled         bool
 )
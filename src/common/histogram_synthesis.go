@@ -0,0 +1,272 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Supported histogram distributions
+const (
+	DistributionLearned     = "learned"     // Resamples the bucket shape of the captured data point
+	DistributionNormal      = "normal"      // Mean and StdDev
+	DistributionLogNormal   = "lognormal"   // Median Mean, shape StdDev (sigma of the underlying normal)
+	DistributionExponential = "exponential" // Mean
+	DistributionUniform     = "uniform"     // Between Min and Max
+)
+
+// HistogramRule is one entry of the histograms list in config.yaml. It applies to every
+// explicit bucket histogram whose metric name matches the Match glob.
+type HistogramRule struct {
+	Match        string   `yaml:"match"`
+	Distribution string   `yaml:"distribution"`
+	Observations int      `yaml:"observations"` // Per interval; 0 follows the captured count (its growth for cumulative histograms)
+	Mean         float64  `yaml:"mean"`
+	StdDev       float64  `yaml:"stddev"`
+	Min          *float64 `yaml:"min"`
+	Max          *float64 `yaml:"max"`
+}
+
+// Validate checks the pattern and the parameters the distribution needs
+func (r HistogramRule) Validate() error {
+	if _, err := path.Match(r.Match, ""); err != nil || r.Match == "" {
+		return fmt.Errorf("invalid match pattern %q", r.Match)
+	}
+	if r.Observations < 0 {
+		return fmt.Errorf("%s: observations must be >= 0, got %d", r.Match, r.Observations)
+	}
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return fmt.Errorf("%s: min %g is greater than max %g", r.Match, *r.Min, *r.Max)
+	}
+	switch r.Distribution {
+	case DistributionLearned:
+	case DistributionNormal:
+		if r.StdDev <= 0 {
+			return fmt.Errorf("%s: normal needs stddev > 0", r.Match)
+		}
+	case DistributionLogNormal:
+		if r.Mean <= 0 || r.StdDev <= 0 {
+			return fmt.Errorf("%s: lognormal needs mean > 0 and stddev > 0", r.Match)
+		}
+	case DistributionExponential:
+		if r.Mean <= 0 {
+			return fmt.Errorf("%s: exponential needs mean > 0", r.Match)
+		}
+	case DistributionUniform:
+		if r.Min == nil || r.Max == nil {
+			return fmt.Errorf("%s: uniform needs both min and max", r.Match)
+		}
+	default:
+		return fmt.Errorf("%s: unknown distribution %q (must be %s, %s, %s, %s or %s)", r.Match, r.Distribution,
+			DistributionLearned, DistributionNormal, DistributionLogNormal, DistributionExponential, DistributionUniform)
+	}
+	return nil
+}
+
+// histogramState is the accumulated distribution of one cumulative histogram series
+type histogramState struct {
+	counts    []uint64
+	count     uint64
+	sum       float64
+	min, max  float64
+	startTime int64
+	captured  uint64 // Count of the captured data point in the previous iteration
+}
+
+// HistogramSynthesizer replaces the captured buckets with freshly sampled observations.
+// Cumulative series accumulate across iterations, so it must be shared across iterations.
+type HistogramSynthesizer struct {
+	rules    []HistogramRule
+	interval time.Duration
+	mu       sync.Mutex
+	series   map[seriesKey]*histogramState
+}
+
+func NewHistogramSynthesizer(rules []HistogramRule, interval time.Duration) *HistogramSynthesizer {
+	return &HistogramSynthesizer{rules: rules, interval: interval, series: make(map[seriesKey]*histogramState)}
+}
+
+func (h *HistogramSynthesizer) ruleFor(name string) *HistogramRule {
+	for i := range h.rules {
+		if ok, _ := path.Match(h.rules[i].Match, name); ok {
+			return &h.rules[i]
+		}
+	}
+	return nil
+}
+
+// Apply samples one iteration of the given interval into one replica's copy of the
+// capture. Call it after the timestamps were refreshed, since cumulative series keep their
+// own start time.
+func (h *HistogramSynthesizer) Apply(metricsFile *MetricsFile, replica int, now int64, interval time.Duration) {
+	if h == nil || len(h.rules) == 0 {
+		return
+	}
+	for r := range metricsFile.ResourceMetrics {
+		for s := range metricsFile.ResourceMetrics[r].ScopeMetrics {
+			metrics := metricsFile.ResourceMetrics[r].ScopeMetrics[s].Metrics
+			for i := range metrics {
+				hist := metrics[i].Histogram
+				if hist == nil {
+					continue
+				}
+				rule := h.ruleFor(metrics[i].Name)
+				if rule == nil {
+					continue
+				}
+				cumulative := hist.AggregationTemporality == 2
+				for p := range hist.DataPoints {
+					h.synthesize(rule, &hist.DataPoints[p], seriesKey{replica, r, s, i, p}, cumulative, now, interval)
+				}
+			}
+		}
+	}
+}
+
+func (h *HistogramSynthesizer) synthesize(rule *HistogramRule, dp *HistogramDataPoint, key seriesKey, cumulative bool, now int64, interval time.Duration) {
+	if len(dp.BucketCounts) != len(dp.ExplicitBounds)+1 {
+		return
+	}
+
+	// Only the map is shared: a series belongs to one replica, and a replica is generated by
+	// one worker at a time, so its state is sampled without holding the lock
+	end, err := strconv.ParseInt(dp.TimeUnixNano, 10, 64)
+	if err != nil {
+		end = now
+		dp.TimeUnixNano = strconv.FormatInt(now, 10)
+	}
+
+	h.mu.Lock()
+	state, seen := h.series[key]
+	if !seen || !cumulative {
+		state = &histogramState{counts: make([]uint64, len(dp.BucketCounts)), min: math.Inf(1), max: math.Inf(-1), startTime: seriesStart(end, interval)}
+		if cumulative {
+			h.series[key] = state
+		}
+	}
+	h.mu.Unlock()
+
+	// Scale the number of observations by the interval, so load profiles that change the
+	// interval keep the configured rate
+	observations := float64(rule.Observations)
+	if observations == 0 {
+		observations = float64(capturedObservations(state, uint64(dp.Count), seen, cumulative))
+	}
+	observations *= float64(interval) / float64(h.interval)
+
+	sample := h.sampler(rule, dp, ReplicaRand(key.replica))
+	for n := int(math.Round(observations)); n > 0; n-- {
+		value := clamp(sample(), rule.Min, rule.Max)
+		state.counts[sort.SearchFloat64s(dp.ExplicitBounds, value)]++
+		state.count++
+		state.sum += value
+		state.min = math.Min(state.min, value)
+		state.max = math.Max(state.max, value)
+	}
+
+	dp.BucketCounts = make([]Uint64String, len(state.counts))
+	for b, count := range state.counts {
+		dp.BucketCounts[b] = Uint64String(count)
+	}
+	dp.Count = Uint64String(state.count)
+	dp.Sum = state.sum
+	dp.Min, dp.Max = nil, nil
+	if state.count > 0 {
+		minValue, maxValue := state.min, state.max
+		dp.Min, dp.Max = &minValue, &maxValue
+	}
+	if cumulative {
+		dp.StartTimeUnixNano = strconv.FormatInt(state.startTime, 10)
+	}
+}
+
+// capturedObservations is the number of observations the capture made in one interval. A
+// delta data point holds exactly that; a cumulative one holds its lifetime total, so only
+// its growth since the previous iteration counts, and nothing on the first one.
+func capturedObservations(state *histogramState, count uint64, seen bool, cumulative bool) uint64 {
+	if !cumulative {
+		return count
+	}
+	previous := state.captured
+	state.captured = count
+	switch {
+	case !seen:
+		return 0
+	case count < previous:
+		// The captured series restarted
+		return count
+	default:
+		return count - previous
+	}
+}
+
+// sampler returns a function drawing one observation from the rule's distribution
+func (h *HistogramSynthesizer) sampler(rule *HistogramRule, dp *HistogramDataPoint, rng *rand.Rand) func() float64 {
+	switch rule.Distribution {
+	case DistributionNormal:
+		return func() float64 { return rule.Mean + rule.StdDev*rng.NormFloat64() }
+	case DistributionLogNormal:
+		return func() float64 { return rule.Mean * math.Exp(rule.StdDev*rng.NormFloat64()) }
+	case DistributionExponential:
+		return func() float64 { return rng.ExpFloat64() * rule.Mean }
+	case DistributionUniform:
+		return func() float64 { return *rule.Min + rng.Float64()*(*rule.Max-*rule.Min) }
+	default:
+		return learnedSampler(dp, rng)
+	}
+}
+
+// learnedSampler picks a bucket in proportion to the captured counts and a value uniformly
+// inside it. The open-ended outer buckets are bounded by the captured min and max, or by
+// the width of their neighbour when those are missing.
+func learnedSampler(dp *HistogramDataPoint, rng *rand.Rand) func() float64 {
+	bounds := dp.ExplicitBounds
+	var total uint64
+	cumulative := make([]uint64, len(dp.BucketCounts))
+	for b, count := range dp.BucketCounts {
+		total += uint64(count)
+		cumulative[b] = total
+	}
+	if total == 0 || len(bounds) == 0 {
+		// Nothing to learn from: sample around the captured mean, or zero
+		mean := 0.0
+		if dp.Count > 0 {
+			mean = dp.Sum / float64(dp.Count)
+		}
+		return func() float64 { return mean }
+	}
+
+	width := 1.0
+	if len(bounds) > 1 {
+		width = bounds[1] - bounds[0]
+	}
+	lowest := bounds[0] - width
+	if dp.Min != nil && *dp.Min < bounds[0] {
+		lowest = *dp.Min
+	}
+	if len(bounds) > 1 {
+		width = bounds[len(bounds)-1] - bounds[len(bounds)-2]
+	}
+	highest := bounds[len(bounds)-1] + width
+	if dp.Max != nil && *dp.Max > bounds[len(bounds)-1] {
+		highest = *dp.Max
+	}
+
+	return func() float64 {
+		target := uint64(rng.Int63n(int64(total))) + 1
+		b := sort.Search(len(cumulative), func(i int) bool { return cumulative[i] >= target })
+		lower, upper := lowest, highest
+		if b > 0 {
+			lower = bounds[b-1]
+		}
+		if b < len(bounds) {
+			upper = bounds[b]
+		}
+		return lower + rng.Float64()*(upper-lower)
+	}
+}
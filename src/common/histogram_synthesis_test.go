@@ -0,0 +1,145 @@
+package common
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func histogramFile(count uint64, temporality int) *MetricsFile {
+	return &MetricsFile{ResourceMetrics: []ResourceMetric{{
+		ScopeMetrics: []ScopeMetric{{
+			Metrics: []Metric{{Name: "http.server.duration", Histogram: &Histogram{
+				AggregationTemporality: temporality,
+				DataPoints: []HistogramDataPoint{{
+					Count:          Uint64String(count),
+					BucketCounts:   []Uint64String{Uint64String(count), 0},
+					ExplicitBounds: []float64{1},
+				}},
+			}}},
+		}},
+	}}}
+}
+
+func histogramPoint(file *MetricsFile) HistogramDataPoint {
+	return file.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Histogram.DataPoints[0]
+}
+
+func TestSynthesizedCountPerInterval(t *testing.T) {
+	const interval = 10 * time.Second
+	for _, tt := range []struct {
+		name         string
+		observations int
+		temporality  int
+		captured     []uint64 // Count of the capture in each iteration
+		want         []uint64 // Count sent in each iteration
+	}{
+		{"cumulative fixed rate", 50, 2, []uint64{1e6, 1e6, 1e6}, []uint64{50, 100, 150}},
+		{"cumulative captured growth", 0, 2, []uint64{1e6, 1e6 + 30, 1e6 + 30, 1e6 + 45}, []uint64{0, 30, 30, 45}},
+		{"cumulative captured restart", 0, 2, []uint64{1e6, 20}, []uint64{0, 20}},
+		{"delta fixed rate", 50, 1, []uint64{1e6, 1e6}, []uint64{50, 50}},
+		{"delta captured count", 0, 1, []uint64{40, 70}, []uint64{40, 70}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			synthesizer := NewHistogramSynthesizer([]HistogramRule{{Match: "http.*", Distribution: DistributionLearned, Observations: tt.observations}}, interval)
+			for i, captured := range tt.captured {
+				file := histogramFile(captured, tt.temporality)
+				synthesizer.Apply(file, 0, time.Now().UnixNano(), interval)
+				if got := uint64(histogramPoint(file).Count); got != tt.want[i] {
+					t.Errorf("iteration %d: count = %d, want %d", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSynthesizedCumulativeWindow(t *testing.T) {
+	const interval = 10 * time.Second
+	synthesizer := NewHistogramSynthesizer([]HistogramRule{{Match: "http.*", Distribution: DistributionLearned, Observations: 5}}, interval)
+
+	base := time.Now().UnixNano()
+	for i := int64(0); i < 3; i++ {
+		file := histogramFile(10, 2)
+		end := base + i*int64(interval)
+		file.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Histogram.DataPoints[0].TimeUnixNano = strconv.FormatInt(end, 10)
+		synthesizer.Apply(file, 0, end+int64(time.Millisecond), interval)
+
+		dp := histogramPoint(file)
+		if want := strconv.FormatInt(base-int64(interval), 10); dp.StartTimeUnixNano != want {
+			t.Errorf("iteration %d: start = %s, want %s, one interval before the first point", i, dp.StartTimeUnixNano, want)
+		}
+		if dp.TimeUnixNano != strconv.FormatInt(end, 10) {
+			t.Errorf("iteration %d: end moved from %d to %s", i, end, dp.TimeUnixNano)
+		}
+	}
+}
+
+func TestSynthesizedHistogramIsConsistent(t *testing.T) {
+	low, high := 0.5, 3.0
+	for _, rule := range []HistogramRule{
+		{Match: "*", Distribution: DistributionLearned, Observations: 1000},
+		{Match: "*", Distribution: DistributionNormal, Observations: 1000, Mean: 2, StdDev: 1, Min: &low, Max: &high},
+		{Match: "*", Distribution: DistributionLogNormal, Observations: 1000, Mean: 1, StdDev: 0.5},
+		{Match: "*", Distribution: DistributionExponential, Observations: 1000, Mean: 1, Max: &high},
+		{Match: "*", Distribution: DistributionUniform, Observations: 1000, Min: &low, Max: &high},
+	} {
+		file := &MetricsFile{ResourceMetrics: []ResourceMetric{{ScopeMetrics: []ScopeMetric{{Metrics: []Metric{{
+			Name: "latency",
+			Histogram: &Histogram{AggregationTemporality: 1, DataPoints: []HistogramDataPoint{{
+				Count:          10,
+				BucketCounts:   []Uint64String{0, 4, 6, 0},
+				ExplicitBounds: []float64{1, 2, 3},
+			}}},
+		}}}}}}}
+		NewHistogramSynthesizer([]HistogramRule{rule}, time.Second).Apply(file, 0, time.Now().UnixNano(), time.Second)
+
+		dp := histogramPoint(file)
+		var total uint64
+		for _, count := range dp.BucketCounts {
+			total += uint64(count)
+		}
+		if uint64(dp.Count) != 1000 || total != 1000 {
+			t.Fatalf("%s: count %d, buckets add up to %d, want 1000", rule.Distribution, dp.Count, total)
+		}
+		if dp.Min == nil || dp.Max == nil || *dp.Min > *dp.Max {
+			t.Fatalf("%s: min %v, max %v", rule.Distribution, dp.Min, dp.Max)
+		}
+		if dp.Sum < *dp.Min*1000 || dp.Sum > *dp.Max*1000 {
+			t.Errorf("%s: sum %g outside count times [min, max] = [%g, %g]", rule.Distribution, dp.Sum, *dp.Min, *dp.Max)
+		}
+		if (rule.Min != nil && *dp.Min < *rule.Min) || (rule.Max != nil && *dp.Max > *rule.Max) {
+			t.Errorf("%s: observed [%g, %g] exceeds the configured bounds", rule.Distribution, *dp.Min, *dp.Max)
+		}
+		if rule.Distribution == DistributionLearned {
+			// Only the captured (1, 2] and (2, 3] buckets had observations, 40:60
+			if dp.BucketCounts[0] != 0 || dp.BucketCounts[3] != 0 {
+				t.Errorf("learned: buckets %v sampled outside the captured ones", dp.BucketCounts)
+			}
+			if share := float64(dp.BucketCounts[1]) / 1000; share < 0.33 || share > 0.47 {
+				t.Errorf("learned: %.2f of the observations in (1, 2], want about 0.4", share)
+			}
+		}
+	}
+}
+
+func TestHistogramRuleValidate(t *testing.T) {
+	low, high := 1.0, 0.5
+	for _, tt := range []struct {
+		rule HistogramRule
+		ok   bool
+	}{
+		{HistogramRule{Match: "http.*", Distribution: DistributionLearned}, true},
+		{HistogramRule{Match: "[", Distribution: DistributionLearned}, false},
+		{HistogramRule{Match: "http.*", Distribution: DistributionLearned, Observations: -1}, false},
+		{HistogramRule{Match: "http.*", Distribution: DistributionNormal}, false},
+		{HistogramRule{Match: "http.*", Distribution: DistributionLogNormal, Mean: 1}, false},
+		{HistogramRule{Match: "http.*", Distribution: DistributionExponential, Mean: 1}, true},
+		{HistogramRule{Match: "http.*", Distribution: DistributionUniform, Min: &low}, false},
+		{HistogramRule{Match: "http.*", Distribution: DistributionLearned, Min: &low, Max: &high}, false},
+		{HistogramRule{Match: "http.*", Distribution: "gamma"}, false},
+	} {
+		if err := tt.rule.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%+v: Validate() = %v, want ok %t", tt.rule, err, tt.ok)
+		}
+	}
+}
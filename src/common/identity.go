@@ -0,0 +1,183 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// ReplacementMap remembers the generated identity for every original value so names stay
+// stable across iterations; it is shared by all replica workers
+type ReplacementMap struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+// LoadReplacements reads previously generated replacements, starting empty if the file is missing
+func LoadReplacements(path string) *ReplacementMap {
+	replacements := &ReplacementMap{m: make(map[string]string)}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &replacements.m)
+	}
+	return replacements
+}
+
+// Save writes the replacements so the next iteration or run reuses the same names
+func (r *ReplacementMap) Save(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if jsonData, err := json.MarshalIndent(r.m, "", "  "); err == nil {
+		_ = os.WriteFile(path, jsonData, 0644)
+	}
+}
+
+// ResetReplacements deletes the saved replacements, so the next LoadReplacements starts empty
+func ResetReplacements(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Resolve returns the stored replacement for key, or stores and returns generate()
+func (r *ReplacementMap) Resolve(key string, generate func() string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if replacement, ok := r.m[key]; ok {
+		return replacement, true
+	}
+	replacement := generate()
+	r.m[key] = replacement
+	return replacement, false
+}
+
+// IdentityRewriter applies the configured rewrite rules to the resources of one replica.
+// Use a new rewriter per replica and feed it every resource of the payload, so repeated
+// node names get distinct node indexes.
+type IdentityRewriter struct {
+	clusterIndex    int
+	rules           []RewriteRule
+	replacements    *ReplacementMap
+	nodeNameCounter map[string]int
+	expanded        bool // Resources were cloned by ExpandCardinality
+}
+
+func NewIdentityRewriter(clusterIndex int, replacements *ReplacementMap) *IdentityRewriter {
+	return &IdentityRewriter{
+		clusterIndex:    clusterIndex,
+		rules:           RewriteRules,
+		replacements:    replacements,
+		nodeNameCounter: make(map[string]int),
+	}
+}
+
+// WithExpandedResources tells the rewriter that the payload went through ExpandCardinality.
+// Its node names are unique already, and resources repeating one belong to the same node,
+// so they keep node index 0 instead of being counted as further nodes.
+func (w *IdentityRewriter) WithExpandedResources() *IdentityRewriter {
+	w.expanded = true
+	return w
+}
+
+// Rewrite applies the resource level rules to resource in place
+func (w *IdentityRewriter) Rewrite(resource *Resource) {
+	w.rewriteAttributes(resource.Attributes, RuleLevelResource, w.nodeIndex(resource))
+}
+
+// RewriteResourceMetric applies the resource level rules to the resource and the data
+// point level rules to every data point of rm
+func (w *IdentityRewriter) RewriteResourceMetric(rm *ResourceMetric) {
+	node := w.nodeIndex(&rm.Resource)
+	w.rewriteAttributes(rm.Resource.Attributes, RuleLevelResource, node)
+	for s := range rm.ScopeMetrics {
+		for m := range rm.ScopeMetrics[s].Metrics {
+			metric := &rm.ScopeMetrics[s].Metrics[m]
+			switch {
+			case metric.Gauge != nil:
+				for p := range metric.Gauge.DataPoints {
+					w.rewriteAttributes(metric.Gauge.DataPoints[p].Attributes, RuleLevelDataPoint, node)
+				}
+			case metric.Sum != nil:
+				for p := range metric.Sum.DataPoints {
+					w.rewriteAttributes(metric.Sum.DataPoints[p].Attributes, RuleLevelDataPoint, node)
+				}
+			case metric.Histogram != nil:
+				for p := range metric.Histogram.DataPoints {
+					w.rewriteAttributes(metric.Histogram.DataPoints[p].Attributes, RuleLevelDataPoint, node)
+				}
+			case metric.ExponentialHistogram != nil:
+				for p := range metric.ExponentialHistogram.DataPoints {
+					w.rewriteAttributes(metric.ExponentialHistogram.DataPoints[p].Attributes, RuleLevelDataPoint, node)
+				}
+			case metric.Summary != nil:
+				for p := range metric.Summary.DataPoints {
+					w.rewriteAttributes(metric.Summary.DataPoints[p].Attributes, RuleLevelDataPoint, node)
+				}
+			}
+		}
+	}
+}
+
+// nodeIndex counts how often the resource's node name was seen before in this replica
+func (w *IdentityRewriter) nodeIndex(resource *Resource) int {
+	for _, attr := range resource.Attributes {
+		if attr.Key != "k8s.node.name" {
+			continue
+		}
+		if w.expanded {
+			return 0
+		}
+		count := w.nodeNameCounter[attr.Value.StringValue]
+		w.nodeNameCounter[attr.Value.StringValue]++
+		return count
+	}
+	return 0
+}
+
+// rewriteAttributes applies the rules of one level in order. Every attribute is rewritten
+// by the first rule that matches it; later rules see the result through Ref.
+func (w *IdentityRewriter) rewriteAttributes(attrs []Attribute, level string, node int) {
+	rewritten := make([]bool, len(attrs))
+	for r := range w.rules {
+		rule := &w.rules[r]
+		if rule.Level != level {
+			continue
+		}
+		for i, attr := range attrs {
+			// Only string values are rewritten; the typed union fields are left alone
+			if rewritten[i] || !rule.Matches(attr.Key) || !attr.Value.IsString() {
+				continue
+			}
+			rewritten[i] = true
+			ctx := RewriteContext{
+				Key:     attr.Key,
+				Value:   attr.Value.StringValue,
+				Replica: w.clusterIndex,
+				Node:    node,
+				Cluster: BaseClusterName,
+				Base:    BaseNodeName,
+				attrs:   attrs,
+			}
+			mappedKey := fmt.Sprintf("%s:%s:%02d:%d", attr.Key, ctx.Value, w.clusterIndex, node)
+			var renderErr error
+			replacement, existed := w.replacements.Resolve(mappedKey, func() string {
+				out, err := rule.Render(ctx)
+				if err != nil || out == "" {
+					// Keep the original value rather than sending an empty attribute
+					renderErr = err
+					return ctx.Value
+				}
+				return out
+			})
+			if renderErr != nil {
+				log.Printf("⚠️ Rewrite rule for %s failed: %v", attr.Key, renderErr)
+			}
+			attrs[i].Value.StringValue = replacement
+			if !existed && level == RuleLevelResource && replacement != ctx.Value {
+				log.Printf("🔄 Rewriting %s: %s -> %s", attr.Key, ctx.Value, replacement)
+			}
+		}
+	}
+}
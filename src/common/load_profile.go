@@ -0,0 +1,149 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// Supported stage types of a load profile
+const (
+	StageRamp  = "ramp"  // Linear change from From to To replicas over Duration
+	StageStep  = "step"  // Start at From, add Step replicas every Hold until To, then hold To once more
+	StageSpike = "spike" // From replicas for Hold, To replicas for Duration, From replicas for Hold
+	StageSoak  = "soak"  // Constant Replicas for Duration
+)
+
+// LoadStage is one entry of the load_profile list in config.yaml
+type LoadStage struct {
+	Type     string        `yaml:"type"`
+	From     int           `yaml:"from"`
+	To       int           `yaml:"to"`
+	Step     int           `yaml:"step"`
+	Replicas int           `yaml:"replicas"`
+	Duration time.Duration `yaml:"duration"`
+	Hold     time.Duration `yaml:"hold"`
+	Interval time.Duration `yaml:"interval"` // Optional, falls back to the global interval
+}
+
+// LoadProfile is a sequence of stages executed back to back
+type LoadProfile []LoadStage
+
+// Validate checks that the stage has every field its type needs
+func (s LoadStage) Validate() error {
+	if s.Interval < 0 {
+		return fmt.Errorf("%s stage: interval must be >= 0, got %s", s.Type, s.Interval)
+	}
+	switch s.Type {
+	case StageRamp:
+		if s.From <= 0 || s.To <= 0 {
+			return fmt.Errorf("ramp stage: from and to must be > 0, got %d and %d", s.From, s.To)
+		}
+		if s.Duration <= 0 {
+			return fmt.Errorf("ramp stage: duration must be > 0")
+		}
+	case StageStep:
+		if s.From <= 0 || s.To <= 0 {
+			return fmt.Errorf("step stage: from and to must be > 0, got %d and %d", s.From, s.To)
+		}
+		if s.Step == 0 || (s.To-s.From)*s.Step < 0 {
+			return fmt.Errorf("step stage: step %d never gets from %d to %d", s.Step, s.From, s.To)
+		}
+		if s.Hold <= 0 {
+			return fmt.Errorf("step stage: hold must be > 0")
+		}
+	case StageSpike:
+		if s.From <= 0 || s.To <= 0 {
+			return fmt.Errorf("spike stage: from and to must be > 0, got %d and %d", s.From, s.To)
+		}
+		if s.Duration <= 0 || s.Hold < 0 {
+			return fmt.Errorf("spike stage: duration must be > 0 and hold >= 0")
+		}
+	case StageSoak:
+		if s.Replicas <= 0 {
+			return fmt.Errorf("soak stage: replicas must be > 0, got %d", s.Replicas)
+		}
+		if s.Duration <= 0 {
+			return fmt.Errorf("soak stage: duration must be > 0")
+		}
+	default:
+		return fmt.Errorf("unknown stage type %q (must be %s, %s, %s or %s)", s.Type, StageRamp, StageStep, StageSpike, StageSoak)
+	}
+	return nil
+}
+
+// Length returns how long the stage runs
+func (s LoadStage) Length() time.Duration {
+	switch s.Type {
+	case StageStep:
+		steps := (s.To-s.From)/s.Step + 1
+		if (s.To-s.From)%s.Step != 0 {
+			steps++
+		}
+		return time.Duration(steps) * s.Hold
+	case StageSpike:
+		return s.Hold + s.Duration + s.Hold
+	default:
+		return s.Duration
+	}
+}
+
+// ReplicasAt returns the replica count the stage asks for at offset from its start
+func (s LoadStage) ReplicasAt(offset time.Duration) int {
+	switch s.Type {
+	case StageRamp:
+		progress := float64(offset) / float64(s.Duration)
+		if progress > 1 {
+			progress = 1
+		}
+		return s.From + int(float64(s.To-s.From)*progress+0.5)
+	case StageStep:
+		replicas := s.From + int(offset/s.Hold)*s.Step
+		if (s.Step > 0 && replicas > s.To) || (s.Step < 0 && replicas < s.To) {
+			replicas = s.To
+		}
+		return replicas
+	case StageSpike:
+		if offset >= s.Hold && offset < s.Hold+s.Duration {
+			return s.To
+		}
+		return s.From
+	default:
+		return s.Replicas
+	}
+}
+
+// At returns the replica count, interval and stage index at elapsed time since the
+// profile started; done is true once every stage has run
+func (p LoadProfile) At(elapsed time.Duration, defaultInterval time.Duration) (replicas int, interval time.Duration, stage int, done bool) {
+	for i, s := range p {
+		length := s.Length()
+		if elapsed < length {
+			interval = s.Interval
+			if interval == 0 {
+				interval = defaultInterval
+			}
+			return s.ReplicasAt(elapsed), interval, i, false
+		}
+		elapsed -= length
+	}
+	return 0, defaultInterval, len(p), true
+}
+
+// Validate checks every stage of the profile
+func (p LoadProfile) Validate() error {
+	for i, s := range p {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("load_profile[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Total returns how long the whole profile runs
+func (p LoadProfile) Total() time.Duration {
+	var total time.Duration
+	for _, s := range p {
+		total += s.Length()
+	}
+	return total
+}
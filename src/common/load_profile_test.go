@@ -0,0 +1,107 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadStageReplicasAt(t *testing.T) {
+	ramp := LoadStage{Type: StageRamp, From: 1, To: 21, Duration: 10 * time.Minute}
+	step := LoadStage{Type: StageStep, From: 20, To: 45, Step: 10, Hold: 5 * time.Minute}
+	down := LoadStage{Type: StageStep, From: 50, To: 20, Step: -10, Hold: time.Minute}
+	spike := LoadStage{Type: StageSpike, From: 50, To: 200, Duration: time.Minute, Hold: 2 * time.Minute}
+	soak := LoadStage{Type: StageSoak, Replicas: 50, Duration: 8 * time.Hour}
+
+	for _, tt := range []struct {
+		name   string
+		stage  LoadStage
+		offset time.Duration
+		want   int
+	}{
+		{"ramp start", ramp, 0, 1},
+		{"ramp halfway", ramp, 5 * time.Minute, 11},
+		{"ramp rounds", ramp, 15 * time.Second, 2}, // 1 + 20*0.025 = 1.5
+		{"ramp end", ramp, 10 * time.Minute, 21},
+		{"ramp past end", ramp, time.Hour, 21},
+		{"step first hold", step, 4 * time.Minute, 20},
+		{"step second hold", step, 5 * time.Minute, 30},
+		{"step clamped to to", step, 15 * time.Minute, 45},
+		{"step down", down, 2 * time.Minute, 30},
+		{"step down clamped", down, 10 * time.Minute, 20},
+		{"spike before", spike, time.Minute, 50},
+		{"spike during", spike, 2 * time.Minute, 200},
+		{"spike after", spike, 3 * time.Minute, 50},
+		{"soak", soak, 4 * time.Hour, 50},
+	} {
+		if got := tt.stage.ReplicasAt(tt.offset); got != tt.want {
+			t.Errorf("%s: ReplicasAt(%s) = %d, want %d", tt.name, tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestLoadStageLength(t *testing.T) {
+	for _, tt := range []struct {
+		stage LoadStage
+		want  time.Duration
+	}{
+		{LoadStage{Type: StageRamp, From: 1, To: 20, Duration: 10 * time.Minute}, 10 * time.Minute},
+		{LoadStage{Type: StageStep, From: 20, To: 50, Step: 10, Hold: 5 * time.Minute}, 20 * time.Minute}, // 20, 30, 40, 50
+		{LoadStage{Type: StageStep, From: 20, To: 45, Step: 10, Hold: 5 * time.Minute}, 20 * time.Minute}, // 20, 30, 40, 45
+		{LoadStage{Type: StageStep, From: 50, To: 20, Step: -10, Hold: time.Minute}, 4 * time.Minute},
+		{LoadStage{Type: StageSpike, From: 50, To: 200, Duration: time.Minute, Hold: 2 * time.Minute}, 5 * time.Minute},
+		{LoadStage{Type: StageSoak, Replicas: 50, Duration: 8 * time.Hour}, 8 * time.Hour},
+	} {
+		if got := tt.stage.Length(); got != tt.want {
+			t.Errorf("%+v: Length() = %s, want %s", tt.stage, got, tt.want)
+		}
+	}
+}
+
+func TestLoadProfileAt(t *testing.T) {
+	profile := LoadProfile{
+		{Type: StageRamp, From: 1, To: 11, Duration: 10 * time.Minute},
+		{Type: StageSoak, Replicas: 30, Duration: time.Hour, Interval: 30 * time.Second},
+	}
+	if total := profile.Total(); total != 70*time.Minute {
+		t.Errorf("Total() = %s, want 1h10m", total)
+	}
+
+	for _, tt := range []struct {
+		elapsed  time.Duration
+		replicas int
+		interval time.Duration
+		stage    int
+		done     bool
+	}{
+		{0, 1, 10 * time.Second, 0, false},
+		{5 * time.Minute, 6, 10 * time.Second, 0, false},
+		{10 * time.Minute, 30, 30 * time.Second, 1, false},
+		{69 * time.Minute, 30, 30 * time.Second, 1, false},
+		{70 * time.Minute, 0, 10 * time.Second, 2, true},
+	} {
+		replicas, interval, stage, done := profile.At(tt.elapsed, 10*time.Second)
+		if replicas != tt.replicas || interval != tt.interval || stage != tt.stage || done != tt.done {
+			t.Errorf("At(%s) = %d, %s, %d, %t; want %d, %s, %d, %t", tt.elapsed,
+				replicas, interval, stage, done, tt.replicas, tt.interval, tt.stage, tt.done)
+		}
+	}
+}
+
+func TestLoadStageValidate(t *testing.T) {
+	for _, tt := range []struct {
+		stage LoadStage
+		ok    bool
+	}{
+		{LoadStage{Type: StageRamp, From: 1, To: 20, Duration: time.Minute}, true},
+		{LoadStage{Type: StageRamp, From: 0, To: 20, Duration: time.Minute}, false},
+		{LoadStage{Type: StageStep, From: 20, To: 50, Step: -10, Hold: time.Minute}, false},
+		{LoadStage{Type: StageStep, From: 20, To: 50, Step: 0, Hold: time.Minute}, false},
+		{LoadStage{Type: StageSpike, From: 50, To: 200, Duration: time.Minute}, true},
+		{LoadStage{Type: StageSoak, Replicas: 50}, false},
+		{LoadStage{Type: "wave", Duration: time.Minute}, false},
+	} {
+		if err := tt.stage.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%+v: Validate() = %v, want ok %t", tt.stage, err, tt.ok)
+		}
+	}
+}
@@ -11,8 +11,9 @@ type ResourceLog struct {
 }
 
 type ScopeLog struct {
-	Scope InstrumentationScope `json:"scope"`
-	Logs  []LogRecord          `json:"logRecords"`
+	Scope     InstrumentationScope `json:"scope"`
+	Logs      []LogRecord          `json:"logRecords"`
+	SchemaUrl string               `json:"schemaUrl,omitempty"`
 }
 
 type LogRecord struct {
@@ -22,9 +23,10 @@ type LogRecord struct {
 	SeverityText         string      `json:"severityText,omitempty"`
 	Body                 LogBody     `json:"body"`
 	Attributes           []Attribute `json:"attributes,omitempty"`
+	Flags                uint32      `json:"flags,omitempty"`
+	TraceID              string      `json:"traceId,omitempty"`
+	SpanID               string      `json:"spanId,omitempty"`
 }
 
-type LogBody struct {
-	StringValue string `json:"stringValue,omitempty"`
-	// You can expand to support intValue, boolValue, etc.
-}
+// LogBody can hold any OTLP value, not only strings
+type LogBody = AttrValue
@@ -20,11 +20,6 @@ type Attribute struct {
 	Value AttrValue `json:"value"`
 }
 
-type AttrValue struct {
-	StringValue string `json:"stringValue,omitempty"`
-	// Add more types if needed (e.g., IntValue, BoolValue, etc.)
-}
-
 type ScopeMetric struct {
 	Scope     InstrumentationScope `json:"scope"`
 	Metrics   []Metric             `json:"metrics"`
@@ -37,12 +32,14 @@ type InstrumentationScope struct {
 }
 
 type Metric struct {
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Unit        string     `json:"unit,omitempty"`
-	Sum         *Sum       `json:"sum,omitempty"`
-	Gauge       *Gauge     `json:"gauge,omitempty"`
-	Histogram   *Histogram `json:"histogram,omitempty"`
+	Name                 string                `json:"name"`
+	Description          string                `json:"description"`
+	Unit                 string                `json:"unit,omitempty"`
+	Sum                  *Sum                  `json:"sum,omitempty"`
+	Gauge                *Gauge                `json:"gauge,omitempty"`
+	Histogram            *Histogram            `json:"histogram,omitempty"`
+	ExponentialHistogram *ExponentialHistogram `json:"exponentialHistogram,omitempty"`
+	Summary              *Summary              `json:"summary,omitempty"`
 }
 
 type Sum struct {
@@ -56,7 +53,17 @@ type Gauge struct {
 }
 
 type Histogram struct {
-	DataPoints []HistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality,omitempty"`
+	DataPoints             []HistogramDataPoint `json:"dataPoints"`
+}
+
+type ExponentialHistogram struct {
+	AggregationTemporality int                             `json:"aggregationTemporality,omitempty"`
+	DataPoints             []ExponentialHistogramDataPoint `json:"dataPoints"`
+}
+
+type Summary struct {
+	DataPoints []SummaryDataPoint `json:"dataPoints"`
 }
 
 type DataPoint struct {
@@ -65,13 +72,68 @@ type DataPoint struct {
 	TimeUnixNano      string      `json:"timeUnixNano"`
 	AsInt             string      `json:"asInt,omitempty"`
 	AsDouble          *float64    `json:"asDouble,omitempty"`
+	Exemplars         []Exemplar  `json:"exemplars,omitempty"`
+	Flags             uint32      `json:"flags,omitempty"`
 }
 
 type HistogramDataPoint struct {
-	StartTimeUnixNano string    `json:"startTimeUnixNano"`
-	TimeUnixNano      string    `json:"timeUnixNano"`
-	Count             uint64    `json:"count"`
-	Sum               float64   `json:"sum"`
-	BucketCounts      []uint64  `json:"bucketCounts"`
-	ExplicitBounds    []float64 `json:"explicitBounds"`
+	Attributes        []Attribute    `json:"attributes,omitempty"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	Count             Uint64String   `json:"count"`
+	Sum               float64        `json:"sum"`
+	BucketCounts      []Uint64String `json:"bucketCounts"`
+	ExplicitBounds    []float64      `json:"explicitBounds"`
+	Exemplars         []Exemplar     `json:"exemplars,omitempty"`
+	Flags             uint32         `json:"flags,omitempty"`
+	Min               *float64       `json:"min,omitempty"`
+	Max               *float64       `json:"max,omitempty"`
+}
+
+type ExponentialHistogramDataPoint struct {
+	Attributes        []Attribute         `json:"attributes,omitempty"`
+	StartTimeUnixNano string              `json:"startTimeUnixNano"`
+	TimeUnixNano      string              `json:"timeUnixNano"`
+	Count             Uint64String        `json:"count"`
+	Sum               *float64            `json:"sum,omitempty"`
+	Scale             int32               `json:"scale"`
+	ZeroCount         Uint64String        `json:"zeroCount"`
+	ZeroThreshold     float64             `json:"zeroThreshold,omitempty"`
+	Positive          *ExponentialBuckets `json:"positive,omitempty"`
+	Negative          *ExponentialBuckets `json:"negative,omitempty"`
+	Exemplars         []Exemplar          `json:"exemplars,omitempty"`
+	Flags             uint32              `json:"flags,omitempty"`
+	Min               *float64            `json:"min,omitempty"`
+	Max               *float64            `json:"max,omitempty"`
+}
+
+// ExponentialBuckets holds consecutive bucket counts starting at index Offset
+type ExponentialBuckets struct {
+	Offset       int32          `json:"offset,omitempty"`
+	BucketCounts []Uint64String `json:"bucketCounts,omitempty"`
+}
+
+type SummaryDataPoint struct {
+	Attributes        []Attribute       `json:"attributes,omitempty"`
+	StartTimeUnixNano string            `json:"startTimeUnixNano"`
+	TimeUnixNano      string            `json:"timeUnixNano"`
+	Count             Uint64String      `json:"count"`
+	Sum               float64           `json:"sum"`
+	QuantileValues    []ValueAtQuantile `json:"quantileValues,omitempty"`
+	Flags             uint32            `json:"flags,omitempty"`
+}
+
+type ValueAtQuantile struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+// Exemplar is a sample measurement attached to a data point, optionally linked to a span
+type Exemplar struct {
+	FilteredAttributes []Attribute `json:"filteredAttributes,omitempty"`
+	TimeUnixNano       string      `json:"timeUnixNano"`
+	AsInt              string      `json:"asInt,omitempty"`
+	AsDouble           *float64    `json:"asDouble,omitempty"`
+	SpanID             string      `json:"spanId,omitempty"`
+	TraceID            string      `json:"traceId,omitempty"`
 }
@@ -42,12 +42,8 @@ func ToOTLPAttributes(attrs []Attribute) []*commonpb.KeyValue {
 	var result []*commonpb.KeyValue
 	for _, attr := range attrs {
 		result = append(result, &commonpb.KeyValue{
-			Key: attr.Key,
-			Value: &commonpb.AnyValue{
-				Value: &commonpb.AnyValue_StringValue{
-					StringValue: attr.Value.StringValue,
-				},
-			},
+			Key:   attr.Key,
+			Value: ToOTLPAnyValue(attr.Value),
 		})
 	}
 	return result
@@ -91,24 +87,77 @@ func ToOTLPMetrics(metrics []Metric) []*metricpb.Metric {
 			}
 			metric.Data = &metricpb.Metric_Sum{Sum: sum}
 		} else if m.Histogram != nil && len(m.Histogram.DataPoints) > 0 {
-			hist := &metricpb.Histogram{}
+			hist := &metricpb.Histogram{
+				AggregationTemporality: metricpb.AggregationTemporality(m.Histogram.AggregationTemporality),
+			}
 			for _, dp := range m.Histogram.DataPoints {
 				var sumPtr *float64
 				if dp.Sum != 0 {
 					sumPtr = &dp.Sum
 				}
+				bucketCounts := make([]uint64, len(dp.BucketCounts))
+				for i, c := range dp.BucketCounts {
+					bucketCounts[i] = uint64(c)
+				}
 				hdp := &metricpb.HistogramDataPoint{
 					StartTimeUnixNano: parseUint(dp.StartTimeUnixNano),
 					TimeUnixNano:      parseUint(dp.TimeUnixNano),
-					Count:             dp.Count,
+					Count:             uint64(dp.Count),
 					Sum:               sumPtr,
-					BucketCounts:      dp.BucketCounts,
+					BucketCounts:      bucketCounts,
 					ExplicitBounds:    dp.ExplicitBounds,
-					Attributes:        []*commonpb.KeyValue{},
+					Attributes:        ToOTLPAttributes(dp.Attributes),
+					Exemplars:         toOTLPExemplars(dp.Exemplars),
+					Flags:             dp.Flags,
+					Min:               dp.Min,
+					Max:               dp.Max,
 				}
 				hist.DataPoints = append(hist.DataPoints, hdp)
 			}
 			metric.Data = &metricpb.Metric_Histogram{Histogram: hist}
+		} else if m.ExponentialHistogram != nil && len(m.ExponentialHistogram.DataPoints) > 0 {
+			expHist := &metricpb.ExponentialHistogram{
+				AggregationTemporality: metricpb.AggregationTemporality(m.ExponentialHistogram.AggregationTemporality),
+			}
+			for _, dp := range m.ExponentialHistogram.DataPoints {
+				expHist.DataPoints = append(expHist.DataPoints, &metricpb.ExponentialHistogramDataPoint{
+					Attributes:        ToOTLPAttributes(dp.Attributes),
+					StartTimeUnixNano: parseUint(dp.StartTimeUnixNano),
+					TimeUnixNano:      parseUint(dp.TimeUnixNano),
+					Count:             uint64(dp.Count),
+					Sum:               dp.Sum,
+					Scale:             dp.Scale,
+					ZeroCount:         uint64(dp.ZeroCount),
+					ZeroThreshold:     dp.ZeroThreshold,
+					Positive:          toOTLPExponentialBuckets(dp.Positive),
+					Negative:          toOTLPExponentialBuckets(dp.Negative),
+					Exemplars:         toOTLPExemplars(dp.Exemplars),
+					Flags:             dp.Flags,
+					Min:               dp.Min,
+					Max:               dp.Max,
+				})
+			}
+			metric.Data = &metricpb.Metric_ExponentialHistogram{ExponentialHistogram: expHist}
+		} else if m.Summary != nil && len(m.Summary.DataPoints) > 0 {
+			summary := &metricpb.Summary{}
+			for _, dp := range m.Summary.DataPoints {
+				sdp := &metricpb.SummaryDataPoint{
+					Attributes:        ToOTLPAttributes(dp.Attributes),
+					StartTimeUnixNano: parseUint(dp.StartTimeUnixNano),
+					TimeUnixNano:      parseUint(dp.TimeUnixNano),
+					Count:             uint64(dp.Count),
+					Sum:               dp.Sum,
+					Flags:             dp.Flags,
+				}
+				for _, q := range dp.QuantileValues {
+					sdp.QuantileValues = append(sdp.QuantileValues, &metricpb.SummaryDataPoint_ValueAtQuantile{
+						Quantile: q.Quantile,
+						Value:    q.Value,
+					})
+				}
+				summary.DataPoints = append(summary.DataPoints, sdp)
+			}
+			metric.Data = &metricpb.Metric_Summary{Summary: summary}
 		}
 
 		result = append(result, metric)
@@ -120,7 +169,9 @@ func toOTLPDataPoint(dp DataPoint) *metricpb.NumberDataPoint {
 	dataPoint := &metricpb.NumberDataPoint{
 		StartTimeUnixNano: parseUint(dp.StartTimeUnixNano),
 		TimeUnixNano:      parseUint(dp.TimeUnixNano),
-		Attributes:        []*commonpb.KeyValue{},
+		Attributes:        ToOTLPAttributes(dp.Attributes),
+		Exemplars:         toOTLPExemplars(dp.Exemplars),
+		Flags:             dp.Flags,
 	}
 
 	if dp.AsDouble != nil {
@@ -145,6 +196,42 @@ func toOTLPDataPoint(dp DataPoint) *metricpb.NumberDataPoint {
 	return dataPoint
 }
 
+func toOTLPExponentialBuckets(buckets *ExponentialBuckets) *metricpb.ExponentialHistogramDataPoint_Buckets {
+	if buckets == nil {
+		return nil
+	}
+	counts := make([]uint64, len(buckets.BucketCounts))
+	for i, c := range buckets.BucketCounts {
+		counts[i] = uint64(c)
+	}
+	return &metricpb.ExponentialHistogramDataPoint_Buckets{
+		Offset:       buckets.Offset,
+		BucketCounts: counts,
+	}
+}
+
+func toOTLPExemplars(exemplars []Exemplar) []*metricpb.Exemplar {
+	var result []*metricpb.Exemplar
+	for _, ex := range exemplars {
+		exemplar := &metricpb.Exemplar{
+			FilteredAttributes: ToOTLPAttributes(ex.FilteredAttributes),
+			TimeUnixNano:       parseUint(ex.TimeUnixNano),
+			SpanId:             decodeHexID(ex.SpanID),
+			TraceId:            decodeHexID(ex.TraceID),
+		}
+		if ex.AsDouble != nil {
+			exemplar.Value = &metricpb.Exemplar_AsDouble{AsDouble: *ex.AsDouble}
+		} else if val, err := strconv.ParseInt(ex.AsInt, 10, 64); err == nil {
+			exemplar.Value = &metricpb.Exemplar_AsInt{AsInt: val}
+		} else {
+			log.Printf("⚠️ Dropping exemplar without a valid value (asInt/asDouble)")
+			continue
+		}
+		result = append(result, exemplar)
+	}
+	return result
+}
+
 func parseUint(s string) uint64 {
 	val, _ := strconv.ParseUint(s, 10, 64)
 	return val
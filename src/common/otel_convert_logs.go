@@ -0,0 +1,53 @@
+package common
+
+import (
+	logscollectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Convert entire LogFile to OTLP ExportLogsServiceRequest
+func ToOTLPLogRequest(logFile LogFile) *logscollectorpb.ExportLogsServiceRequest {
+	var otlpResourceLogs []*logspb.ResourceLogs
+
+	for _, rl := range logFile.ResourceLogs {
+		var otlpScopeLogs []*logspb.ScopeLogs
+		for _, sl := range rl.ScopeLogs {
+			otlpScopeLogs = append(otlpScopeLogs, &logspb.ScopeLogs{
+				Scope:      ToOTLPScope(sl.Scope),
+				SchemaUrl:  sl.SchemaUrl,
+				LogRecords: ToOTLPLogRecords(sl.Logs),
+			})
+		}
+
+		otlpResourceLogs = append(otlpResourceLogs, &logspb.ResourceLogs{
+			Resource: &resourcepb.Resource{
+				Attributes: ToOTLPAttributes(rl.Resource.Attributes),
+			},
+			ScopeLogs: otlpScopeLogs,
+			SchemaUrl: rl.SchemaUrl,
+		})
+	}
+
+	return &logscollectorpb.ExportLogsServiceRequest{
+		ResourceLogs: otlpResourceLogs,
+	}
+}
+
+func ToOTLPLogRecords(records []LogRecord) []*logspb.LogRecord {
+	var result []*logspb.LogRecord
+	for _, r := range records {
+		result = append(result, &logspb.LogRecord{
+			TimeUnixNano:         parseUint(r.TimeUnixNano),
+			ObservedTimeUnixNano: parseUint(r.ObservedTimeUnixNano),
+			SeverityNumber:       logspb.SeverityNumber(r.SeverityNumber),
+			SeverityText:         r.SeverityText,
+			Body:                 ToOTLPAnyValue(r.Body),
+			Attributes:           ToOTLPAttributes(r.Attributes),
+			Flags:                r.Flags,
+			TraceId:              decodeHexID(r.TraceID),
+			SpanId:               decodeHexID(r.SpanID),
+		})
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"encoding/hex"
+	"log"
+
+	tracecollectorpb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// Convert entire TraceFile to OTLP ExportTraceServiceRequest
+func ToOTLPTraceRequest(traceFile TraceFile) *tracecollectorpb.ExportTraceServiceRequest {
+	var otlpResourceSpans []*tracepb.ResourceSpans
+
+	for _, rs := range traceFile.ResourceSpans {
+		var otlpScopeSpans []*tracepb.ScopeSpans
+		for _, ss := range rs.ScopeSpans {
+			otlpScopeSpans = append(otlpScopeSpans, &tracepb.ScopeSpans{
+				Scope:     ToOTLPScope(ss.Scope),
+				SchemaUrl: ss.SchemaUrl,
+				Spans:     ToOTLPSpans(ss.Spans),
+			})
+		}
+
+		otlpResourceSpans = append(otlpResourceSpans, &tracepb.ResourceSpans{
+			Resource: &resourcepb.Resource{
+				Attributes: ToOTLPAttributes(rs.Resource.Attributes),
+			},
+			ScopeSpans: otlpScopeSpans,
+			SchemaUrl:  rs.SchemaUrl,
+		})
+	}
+
+	return &tracecollectorpb.ExportTraceServiceRequest{
+		ResourceSpans: otlpResourceSpans,
+	}
+}
+
+func ToOTLPSpans(spans []Span) []*tracepb.Span {
+	var result []*tracepb.Span
+	for _, s := range spans {
+		span := &tracepb.Span{
+			TraceId:           decodeHexID(s.TraceID),
+			SpanId:            decodeHexID(s.SpanID),
+			TraceState:        s.TraceState,
+			ParentSpanId:      decodeHexID(s.ParentSpanID),
+			Name:              s.Name,
+			Kind:              tracepb.Span_SpanKind(s.Kind),
+			StartTimeUnixNano: parseUint(s.StartTimeUnixNano),
+			EndTimeUnixNano:   parseUint(s.EndTimeUnixNano),
+			Attributes:        ToOTLPAttributes(s.Attributes),
+			Status: &tracepb.Status{
+				Code:    tracepb.Status_StatusCode(s.Status.Code),
+				Message: s.Status.Message,
+			},
+		}
+		for _, e := range s.Events {
+			span.Events = append(span.Events, &tracepb.Span_Event{
+				TimeUnixNano: parseUint(e.TimeUnixNano),
+				Name:         e.Name,
+				Attributes:   ToOTLPAttributes(e.Attributes),
+			})
+		}
+		for _, l := range s.Links {
+			span.Links = append(span.Links, &tracepb.Span_Link{
+				TraceId:    decodeHexID(l.TraceID),
+				SpanId:     decodeHexID(l.SpanID),
+				TraceState: l.TraceState,
+				Attributes: ToOTLPAttributes(l.Attributes),
+			})
+		}
+		result = append(result, span)
+	}
+	return result
+}
+
+// decodeHexID turns an OTLP/JSON hex trace or span ID into its raw bytes
+func decodeHexID(id string) []byte {
+	if id == "" {
+		return nil
+	}
+	raw, err := hex.DecodeString(id)
+	if err != nil {
+		log.Printf("⚠️ Dropping invalid trace/span ID '%s': %v", id, err)
+		return nil
+	}
+	return raw
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"flag"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Seed drives every random choice in generated payloads; 0 picks one from the clock
+var Seed int64
+
+// RegisterSeedFlag adds -seed to the binaries that generate randomized payloads
+func RegisterSeedFlag() {
+	flag.Int64Var(&Seed, "seed", 0, "Seed for all generated randomness (0 = random, logged at startup)")
+}
+
+// InitRandom picks a seed if none was given and logs it, so any run can be reproduced
+func InitRandom() {
+	if Seed == 0 {
+		Seed = time.Now().UnixNano()
+	}
+	log.Printf("🎲 Random seed: %d (pass -seed %d to reproduce this run)", Seed, Seed)
+}
+
+var (
+	replicaRandsMu sync.Mutex
+	replicaRands   = make(map[int]*rand.Rand)
+)
+
+// ReplicaRand returns the random source of one replica. A replica is always processed by
+// the same pool worker, in order, so its draws depend only on the seed and the config and
+// not on how the replicas interleave. It must not be used from another goroutine.
+func ReplicaRand(replica int) *rand.Rand {
+	replicaRandsMu.Lock()
+	defer replicaRandsMu.Unlock()
+	r, ok := replicaRands[replica]
+	if !ok {
+		// Spread the replica index over the seed so neighbouring seeds do not share streams
+		mix := uint64(replica+1) * 0x9E3779B97F4A7C15
+		r = rand.New(rand.NewSource(Seed ^ int64(mix)))
+		replicaRands[replica] = r
+	}
+	return r
+}
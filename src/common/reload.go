@@ -0,0 +1,161 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReloadableKeys are the config keys a running binary picks up at its next iteration.
+// Changes to any other key are logged and need a restart.
+var ReloadableKeys = []string{"no_replicas", "interval", "rewrite_rules", "collectorURL", "grpc_endpoint", "endpoints", "balancing", "headers", "auth", "tls", "protocol", "compression", "timeout"}
+
+// SinkKeys are the reloadable keys the sinks are built from; the sink has to be recreated
+// when one of them changes
+var SinkKeys = []string{"collectorURL", "grpc_endpoint", "endpoints", "balancing", "headers", "auth", "tls", "protocol", "compression", "timeout"}
+
+// configPollInterval is how often the config file is checked for changes
+const configPollInterval = 2 * time.Second
+
+// ConfigChanges lists the config keys applied by a reload
+type ConfigChanges []string
+
+// Has reports whether any of keys changed
+func (c ConfigChanges) Has(keys ...string) bool {
+	for _, key := range keys {
+		if slices.Contains(c, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigWatcher marks the config file for reloading on SIGHUP or when it changes. The
+// reload itself happens when the binary calls Reload between two iterations, so an
+// iteration never sees half of a change.
+type ConfigWatcher struct {
+	path    string
+	section string
+	pending atomic.Bool
+}
+
+// WatchConfig starts watching the config file the binary was started with
+func WatchConfig(path string, section string) *ConfigWatcher {
+	w := &ConfigWatcher{path: path, section: section}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Printf("🔄 SIGHUP received, reloading %s at the next iteration", path)
+			w.pending.Store(true)
+		}
+	}()
+
+	go func() {
+		last := configModTime(path)
+		for range time.Tick(configPollInterval) {
+			if modTime := configModTime(path); !modTime.Equal(last) {
+				last = modTime
+				log.Printf("🔄 %s changed, reloading at the next iteration", path)
+				w.pending.Store(true)
+			}
+		}
+	}()
+
+	log.Printf("👀 Watching %s for changes (or send SIGHUP)", path)
+	return w
+}
+
+func configModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Reload re-reads the config file if a reload is pending and applies the changed
+// ReloadableKeys. A file with problems is not applied at all. It must be called while no
+// replica is being generated or sent.
+func (w *ConfigWatcher) Reload() ConfigChanges {
+	if w == nil || !w.pending.Swap(false) {
+		return nil
+	}
+	cfg, problems := readConfig(w.path, w.section)
+	if len(problems) > 0 {
+		log.Printf("❌ Not reloading %s, it has %d problem(s):", w.path, len(problems))
+		for _, problem := range problems {
+			log.Printf("  - %s", problem)
+		}
+		return nil
+	}
+
+	var changes ConfigChanges
+	next := loadedConfig
+	for _, key := range changedConfigKeys(loadedConfig, cfg) {
+		if !slices.Contains(ReloadableKeys, key) {
+			log.Printf("⚠️ %s changed, restart to apply it", key)
+			continue
+		}
+		before, after := configField(&next, key), configField(&cfg, key)
+		log.Printf("🔄 Reloaded %s: %s -> %s", key, describeConfigValue(before), describeConfigValue(after))
+		before.Set(after)
+		changes = append(changes, key)
+	}
+	if len(changes) == 0 {
+		log.Printf("🔄 Reloaded %s, nothing to apply", w.path)
+		return nil
+	}
+	applyConfig(next)
+	return changes
+}
+
+// changedConfigKeys returns the keys whose values differ between two configs
+func changedConfigKeys(a, b configStruct) ConfigChanges {
+	var changed ConfigChanges
+	t := reflect.TypeOf(a)
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if !configValuesEqual(reflect.ValueOf(a).Field(i), reflect.ValueOf(b).Field(i)) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// configField returns the settable field of cfg with the given yaml key
+func configField(cfg *configStruct, key string) reflect.Value {
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ","); name == key {
+			return v.Field(i)
+		}
+	}
+	panic("unknown config key " + key)
+}
+
+// configValuesEqual compares two config values as they would be written in config.yaml,
+// which ignores compiled state such as the templates of rewrite rules
+func configValuesEqual(a, b reflect.Value) bool {
+	aYAML, aErr := yaml.Marshal(a.Interface())
+	bYAML, bErr := yaml.Marshal(b.Interface())
+	return aErr == nil && bErr == nil && bytes.Equal(aYAML, bYAML)
+}
+
+func describeConfigValue(v reflect.Value) string {
+	if v.Kind() == reflect.Slice {
+		return fmt.Sprintf("%d entries", v.Len())
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
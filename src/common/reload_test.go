@@ -0,0 +1,116 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestChangedConfigKeys(t *testing.T) {
+	base := configStruct{NoReplicas: 2, Interval: 10 * time.Second, Workers: 4, RewriteRules: compiledRules(t, DefaultRewriteRules()...)}
+
+	for _, tt := range []struct {
+		name   string
+		change func(cfg *configStruct)
+		want   ConfigChanges
+	}{
+		{"nothing", func(cfg *configStruct) {}, nil},
+		{"replicas and interval", func(cfg *configStruct) { cfg.NoReplicas, cfg.Interval = 5, time.Second }, ConfigChanges{"no_replicas", "interval"}},
+		{"restart only", func(cfg *configStruct) { cfg.Workers = 8 }, ConfigChanges{"workers"}},
+		{"rewrite rule value", func(cfg *configStruct) {
+			cfg.RewriteRules = slices.Clone(cfg.RewriteRules)
+			cfg.RewriteRules[0].Value = "{{.Value}}"
+		}, ConfigChanges{"rewrite_rules"}},
+		{"recompiled rules", func(cfg *configStruct) { cfg.RewriteRules = compiledRules(t, DefaultRewriteRules()...) }, nil},
+		{"header added", func(cfg *configStruct) { cfg.Headers = []HeaderConfig{{Name: "X-Token", Secret: Secret{Value: "t"}}} }, ConfigChanges{"headers"}},
+	} {
+		next := base
+		tt.change(&next)
+		if got := changedConfigKeys(base, next); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: changed %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfigChangesHas(t *testing.T) {
+	changes := ConfigChanges{"no_replicas", "tls"}
+	if !changes.Has(SinkKeys...) || !changes.Has("no_replicas") {
+		t.Errorf("%v: Has must find tls among the sink keys and no_replicas", changes)
+	}
+	if changes.Has("rewrite_rules") || ConfigChanges(nil).Has(SinkKeys...) {
+		t.Errorf("%v: Has must not find keys that did not change", changes)
+	}
+}
+
+// writeConfig writes a metrics_loadgen config with the given lines after the version
+func writeConfig(t *testing.T, path string, lines string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("version: 1\n"+lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	old := loadedConfig
+	t.Cleanup(func() { applyConfig(old) })
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeConfig(t, path, "no_replicas: 2\nworkers: 4\n")
+	cfg, problems := readConfig(path, "metrics_loadgen")
+	if len(problems) > 0 {
+		t.Fatalf("initial config: %v", problems)
+	}
+	applyConfig(cfg)
+	w := &ConfigWatcher{path: path, section: "metrics_loadgen"}
+
+	for _, tt := range []struct {
+		name     string
+		config   string // Empty to reload without a pending change
+		want     ConfigChanges
+		replicas int
+		workers  int
+	}{
+		{"not pending", "", nil, 2, 4},
+		{"restart only", "no_replicas: 2\nworkers: 8\n", nil, 2, 4},
+		{"reloadable and restart only", "no_replicas: 5\nworkers: 8\n", ConfigChanges{"no_replicas"}, 5, 4},
+		{"problems keep the current config", "no_replicas: 9\nbogus: true\n", nil, 5, 4},
+		{"rewrite rules", "no_replicas: 5\nworkers: 8\nrewrite_rules:\n  - key: host.name\n    value: \"{{.Value}}-x\"\n",
+			ConfigChanges{"rewrite_rules"}, 5, 4},
+	} {
+		if tt.config != "" {
+			writeConfig(t, path, tt.config)
+			w.pending.Store(true)
+		}
+		changes := w.Reload()
+		if !slices.Equal(changes, tt.want) || NoReplicas != tt.replicas || Workers != tt.workers {
+			t.Errorf("%s: changes %v, %d replicas, %d workers; want %v, %d, %d", tt.name,
+				changes, NoReplicas, Workers, tt.want, tt.replicas, tt.workers)
+		}
+	}
+	if len(RewriteRules) != 1 || RewriteRules[0].Key != "host.name" {
+		t.Errorf("rewrite rules %+v, want the reloaded host.name rule", RewriteRules)
+	}
+}
+
+func TestResetReplacements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replacements.json")
+	replacements := LoadReplacements(path)
+	replacements.Resolve("node-a", func() string { return "demo-node-AA-01" })
+	replacements.Save(path)
+
+	if err := ResetReplacements(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after the reset: %v", path, err)
+	}
+	if got, _ := LoadReplacements(path).Resolve("node-a", func() string { return "new" }); got != "new" {
+		t.Errorf("node-a resolved to %q after the reset, want a new identity", got)
+	}
+	if err := ResetReplacements(path); err != nil {
+		t.Errorf("resetting twice: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"sync"
+)
+
+// ReplicaPool runs replica jobs on a fixed number of workers, which bounds the number of
+// requests in flight. Jobs for the same replica always land on the same worker queue, so
+// the payloads of one replica are generated and sent in order.
+type ReplicaPool struct {
+	queues []chan func()
+	wg     sync.WaitGroup
+}
+
+func NewReplicaPool(workers int, queueSize int) *ReplicaPool {
+	p := &ReplicaPool{queues: make([]chan func(), workers)}
+	for i := range p.queues {
+		p.queues[i] = make(chan func(), queueSize)
+		p.wg.Add(1)
+		go func(queue chan func()) {
+			defer p.wg.Done()
+			for job := range queue {
+				job()
+			}
+		}(p.queues[i])
+	}
+	Telemetry.watchPool(p)
+	return p
+}
+
+// Submit queues a job for the given replica, blocking while that worker's queue is full
+func (p *ReplicaPool) Submit(replica int, job func()) {
+	p.queues[replica%len(p.queues)] <- job
+}
+
+// Pending returns the number of jobs waiting in the queues
+func (p *ReplicaPool) Pending() int {
+	n := 0
+	for _, queue := range p.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// Close stops accepting jobs and waits for the queued ones to finish
+func (p *ReplicaPool) Close() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReplicaPoolOrderAndConcurrency(t *testing.T) {
+	for _, tt := range []struct {
+		name                 string
+		workers, queueSize   int
+		replicas, perReplica int
+	}{
+		{"one worker", 1, 1, 4, 20},
+		{"fewer workers than replicas", 3, 2, 10, 20},
+		{"more workers than replicas", 8, 4, 3, 20},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewReplicaPool(tt.workers, tt.queueSize)
+
+			var (
+				mu       sync.Mutex
+				order    = make(map[int][]int)
+				inFlight atomic.Int32
+				peak     atomic.Int32
+			)
+			for job := 0; job < tt.perReplica; job++ {
+				for replica := 0; replica < tt.replicas; replica++ {
+					pool.Submit(replica, func() {
+						if n := inFlight.Add(1); n > peak.Load() {
+							peak.Store(n)
+						}
+						time.Sleep(time.Duration(job%3) * 100 * time.Microsecond)
+						inFlight.Add(-1)
+
+						mu.Lock()
+						order[replica] = append(order[replica], job)
+						mu.Unlock()
+					})
+				}
+			}
+			pool.Close()
+
+			for replica := 0; replica < tt.replicas; replica++ {
+				jobs := order[replica]
+				if len(jobs) != tt.perReplica {
+					t.Fatalf("replica %d: ran %d jobs, want %d", replica, len(jobs), tt.perReplica)
+				}
+				for i, job := range jobs {
+					if job != i {
+						t.Fatalf("replica %d: jobs ran in order %v", replica, jobs)
+					}
+				}
+			}
+			if got := int(peak.Load()); got > tt.workers {
+				t.Errorf("%d jobs ran at once, want at most %d workers", got, tt.workers)
+			}
+			if pending := pool.Pending(); pending != 0 {
+				t.Errorf("%d jobs pending after Close", pending)
+			}
+		})
+	}
+}
@@ -0,0 +1,526 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logscollectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	tracecollectorpb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// Supported report formats
+const (
+	ReportText = "text"
+	ReportJSON = "json"
+)
+
+// ReportConfig is the report section of config.yaml
+type ReportConfig struct {
+	Interval time.Duration `yaml:"interval"` // Periodic summaries of the last interval (0 = final report only)
+	Format   string        `yaml:"format"`   // text or json, on stdout
+	File     string        `yaml:"file"`     // Optional: the final report is also written here as JSON
+}
+
+// Validate checks the format and interval
+func (c ReportConfig) Validate() error {
+	if c.Interval < 0 {
+		return fmt.Errorf("interval must be >= 0, got %s", c.Interval)
+	}
+	if c.Format != ReportText && c.Format != ReportJSON {
+		return fmt.Errorf("unknown format %q (must be %s or %s)", c.Format, ReportText, ReportJSON)
+	}
+	return nil
+}
+
+// Report collects what the sinks were handed during the run; nil until StartReport is called
+var Report *LoadReport
+
+// LoadReport keeps the counters of the whole run, of the current periodic window and of
+// the current scenario phase
+type LoadReport struct {
+	mu     sync.Mutex
+	total  *reportWindow
+	window *reportWindow
+	phase  *reportWindow // nil outside of scenario phases
+	phases []PhaseSummary
+	series map[uint64]struct{} // Distinct metric time series sent successfully
+}
+
+type reportWindow struct {
+	start     time.Time
+	endpoints map[string]*endpointCounters
+}
+
+type endpointCounters struct {
+	signal   string
+	requests int64
+	retries  int64
+	failed   int64
+	items    int64
+	bytes    int64
+	errors   map[string]int64
+	latency  latencyHistogram
+}
+
+func newReportWindow(start time.Time) *reportWindow {
+	return &reportWindow{start: start, endpoints: make(map[string]*endpointCounters)}
+}
+
+// StartReport enables reporting and prints a summary every ReportSettings.Interval
+func StartReport() {
+	now := time.Now()
+	Report = &LoadReport{total: newReportWindow(now), window: newReportWindow(now), series: make(map[uint64]struct{})}
+	if ReportSettings.Interval > 0 {
+		go func() {
+			for range time.Tick(ReportSettings.Interval) {
+				Report.printWindow()
+			}
+		}()
+	}
+}
+
+// FinishReport prints the report of the whole run and writes it to the report file
+func FinishReport() {
+	if Report == nil {
+		return
+	}
+	summary := Report.summarize(Report.total, time.Now(), true)
+	printSummary("🏁 Final report", summary.ReportSummary)
+	if ReportSettings.File == "" {
+		return
+	}
+	path, err := ExpandPath(ReportSettings.File)
+	if err != nil {
+		path = ReportSettings.File
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		log.Printf("❌ Failed to write report to %s: %v", path, err)
+		return
+	}
+	fmt.Printf("📜 Report written to %s\n", path)
+}
+
+// StartReportPhase starts counting a scenario phase separately from the rest of the run
+func StartReportPhase(name string) {
+	if Report == nil {
+		return
+	}
+	Report.mu.Lock()
+	defer Report.mu.Unlock()
+	Report.phase = newReportWindow(time.Now())
+	Report.phases = append(Report.phases, PhaseSummary{Name: name})
+}
+
+// FinishReportPhase prints the report of the current scenario phase and keeps it for the
+// final report
+func FinishReportPhase() {
+	if Report == nil || Report.phase == nil {
+		return
+	}
+	summary := Report.summarize(Report.phase, time.Now(), false)
+	Report.mu.Lock()
+	Report.phase = nil
+	last := &Report.phases[len(Report.phases)-1]
+	last.ReportSummary = summary.ReportSummary
+	name := last.Name
+	Report.mu.Unlock()
+	printSummary(fmt.Sprintf("🏁 Phase %s report", name), summary.ReportSummary)
+}
+
+// Record counts one logical export, which took retries attempts beyond the first. It is
+// safe to call on a nil report.
+func (r *LoadReport) Record(endpoint string, req proto.Message, bytes int, latency time.Duration, retries int, err error) {
+	if r == nil {
+		return
+	}
+	signal, _ := signalOf(req)
+	_, items := CountItems(req)
+	var series []uint64
+	if err == nil {
+		series = seriesHashes(req)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, w := range []*reportWindow{r.total, r.window, r.phase} {
+		if w == nil {
+			continue
+		}
+		c, ok := w.endpoints[endpoint]
+		if !ok {
+			c = &endpointCounters{signal: signal.name, errors: make(map[string]int64)}
+			w.endpoints[endpoint] = c
+		}
+		c.requests++
+		c.retries += int64(retries)
+		c.bytes += int64(bytes)
+		c.latency.add(latency)
+		if err != nil {
+			c.failed++
+			c.errors[errorStatus(err)]++
+		} else {
+			c.items += int64(items)
+		}
+	}
+	for _, h := range series {
+		r.series[h] = struct{}{}
+	}
+}
+
+// reportingSink records every request handed to the sink below it as one entry of the
+// report, however many attempts its retries needed and whatever kind of sink it is
+type reportingSink struct {
+	name string // The endpoint, file or sink type the entry is counted under
+	next Sink
+}
+
+func (s *reportingSink) Export(ctx context.Context, req proto.Message) error {
+	if Report == nil {
+		return s.next.Export(ctx, req)
+	}
+	attempts := &exportAttempts{}
+	start := time.Now()
+	err := s.next.Export(context.WithValue(ctx, exportAttemptsKey{}, attempts), req)
+	bytes := attempts.bytes
+	if attempts.count == 0 {
+		// Sinks that write nothing, like null, are counted with the protobuf size
+		bytes = proto.Size(req)
+	}
+	Report.Record(s.name, req, bytes, time.Since(start), max(attempts.count-1, 0), err)
+	return err
+}
+
+func (s *reportingSink) Close() error {
+	return s.next.Close()
+}
+
+// exportAttempts collects the attempts of one logical export below a reportingSink
+type exportAttempts struct {
+	count int
+	bytes int // Of the last attempt, as sent on the wire
+}
+
+type exportAttemptsKey struct{}
+
+// recordAttempt tells the reportingSink above, if any, that bytes were sent in one attempt
+func recordAttempt(ctx context.Context, bytes int) {
+	if attempts, ok := ctx.Value(exportAttemptsKey{}).(*exportAttempts); ok {
+		attempts.count++
+		attempts.bytes = bytes
+	}
+}
+
+func (r *LoadReport) printWindow() {
+	r.mu.Lock()
+	window := r.window
+	r.window = newReportWindow(time.Now())
+	r.mu.Unlock()
+	printSummary("📜 Report", r.summarize(window, time.Now(), false).ReportSummary)
+}
+
+// errorStatus is the key errors are counted under: the HTTP status, the gRPC code, or
+// "transport" when no response was received
+func errorStatus(err error) string {
+	var exportErr *ExportError
+	if errors.As(err, &exportErr) {
+		if exportErr.StatusCode != 0 {
+			return strconv.Itoa(exportErr.StatusCode)
+		}
+		if exportErr.GRPCCode != codes.OK {
+			return exportErr.GRPCCode.String()
+		}
+	}
+	return "transport"
+}
+
+// ReportSummary is the report of one window, as printed and written to the report file
+type ReportSummary struct {
+	Start           time.Time         `json:"start"`
+	End             time.Time         `json:"end"`
+	DurationSeconds float64           `json:"duration_seconds"`
+	MTS             *int              `json:"mts,omitempty"` // Final report only
+	Endpoints       []EndpointSummary `json:"endpoints"`
+}
+
+// PhaseSummary is the report of one scenario phase
+type PhaseSummary struct {
+	Name string `json:"name"`
+	ReportSummary
+}
+
+// FinalSummary is the report of the whole run, with the report of every scenario phase
+type FinalSummary struct {
+	ReportSummary
+	Phases []PhaseSummary `json:"phases,omitempty"`
+}
+
+type EndpointSummary struct {
+	Endpoint       string           `json:"endpoint"`
+	Signal         string           `json:"signal"`
+	Requests       int64            `json:"requests"` // Exports handed to the sink, however often they were retried
+	Retries        int64            `json:"retries"`  // Attempts beyond the first one of every request
+	Failed         int64            `json:"failed"`
+	Items          int64            `json:"items"` // Data points, spans or log records sent successfully
+	Bytes          int64            `json:"bytes"` // As sent on the wire
+	RequestsPerSec float64          `json:"requests_per_second"`
+	ItemsPerSec    float64          `json:"items_per_second"`
+	BytesPerSec    float64          `json:"bytes_per_second"`
+	LatencyMs      LatencySummary   `json:"latency_ms"`
+	Errors         map[string]int64 `json:"errors,omitempty"`
+}
+
+type LatencySummary struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+func (r *LoadReport) summarize(w *reportWindow, end time.Time, final bool) FinalSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seconds := end.Sub(w.start).Seconds()
+	summary := FinalSummary{ReportSummary: ReportSummary{Start: w.start, End: end, DurationSeconds: seconds}}
+	if final {
+		mts := len(r.series)
+		summary.MTS = &mts
+		summary.Phases = append([]PhaseSummary(nil), r.phases...)
+	}
+	for endpoint, c := range w.endpoints {
+		errs := make(map[string]int64, len(c.errors))
+		for status, count := range c.errors {
+			errs[status] = count
+		}
+		summary.Endpoints = append(summary.Endpoints, EndpointSummary{
+			Endpoint:       endpoint,
+			Signal:         c.signal,
+			Requests:       c.requests,
+			Retries:        c.retries,
+			Failed:         c.failed,
+			Items:          c.items,
+			Bytes:          c.bytes,
+			RequestsPerSec: float64(c.requests) / seconds,
+			ItemsPerSec:    float64(c.items) / seconds,
+			BytesPerSec:    float64(c.bytes) / seconds,
+			LatencyMs: LatencySummary{
+				P50: c.latency.quantile(0.50),
+				P90: c.latency.quantile(0.90),
+				P99: c.latency.quantile(0.99),
+				Max: float64(c.latency.max) / float64(time.Millisecond),
+			},
+			Errors: errs,
+		})
+	}
+	sort.Slice(summary.Endpoints, func(i, j int) bool { return summary.Endpoints[i].Endpoint < summary.Endpoints[j].Endpoint })
+	return summary
+}
+
+// printSummary writes a summary to stdout in the configured format. It does not go through
+// log, so reports are shown even without -I.
+func printSummary(title string, s ReportSummary) {
+	if ReportSettings.Format == ReportJSON {
+		data, _ := json.Marshal(s)
+		fmt.Println(string(data))
+		return
+	}
+
+	itemNames := map[string]string{"metrics": "datapoints", "traces": "spans", "logs": "records"}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s)\n", title, time.Duration(s.DurationSeconds*float64(time.Second)).Round(time.Second))
+	if len(s.Endpoints) == 0 {
+		b.WriteString("  nothing sent\n")
+	}
+	for _, e := range s.Endpoints {
+		fmt.Fprintf(&b, "  %s %s\n", e.Signal, e.Endpoint)
+		fmt.Fprintf(&b, "    %d requests (%.1f/s), %d retries, %d failed, %d %s (%.1f/s), %s (%s/s)\n",
+			e.Requests, e.RequestsPerSec, e.Retries, e.Failed, e.Items, itemNames[e.Signal], e.ItemsPerSec, formatBytes(float64(e.Bytes)), formatBytes(e.BytesPerSec))
+		fmt.Fprintf(&b, "    latency p50 %.1fms, p90 %.1fms, p99 %.1fms, max %.1fms\n", e.LatencyMs.P50, e.LatencyMs.P90, e.LatencyMs.P99, e.LatencyMs.Max)
+		if len(e.Errors) > 0 {
+			statuses := make([]string, 0, len(e.Errors))
+			for status := range e.Errors {
+				statuses = append(statuses, status)
+			}
+			sort.Strings(statuses)
+			for i, status := range statuses {
+				statuses[i] = fmt.Sprintf("%s: %d", status, e.Errors[status])
+			}
+			fmt.Fprintf(&b, "    errors %s\n", strings.Join(statuses, ", "))
+		}
+	}
+	if s.MTS != nil {
+		fmt.Fprintf(&b, "  %d distinct metric time series\n", *s.MTS)
+	}
+	fmt.Print(b.String())
+}
+
+func formatBytes(n float64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.2f GB", n/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.2f MB", n/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", n/(1<<10))
+	default:
+		return fmt.Sprintf("%.0f B", n)
+	}
+}
+
+// latencyHistogram stores latencies in buckets growing by 5%, which bounds the error of
+// every percentile to 5% while using constant memory however long the run is
+type latencyHistogram struct {
+	buckets map[int]int64
+	count   int64
+	max     time.Duration
+}
+
+const latencyGrowth = 1.05
+
+func (h *latencyHistogram) add(d time.Duration) {
+	if h.buckets == nil {
+		h.buckets = make(map[int]int64)
+	}
+	bucket := 0
+	if d > time.Microsecond {
+		bucket = int(math.Ceil(math.Log(float64(d)/float64(time.Microsecond)) / math.Log(latencyGrowth)))
+	}
+	h.buckets[bucket]++
+	h.count++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// quantile returns the upper bound of the bucket holding quantile q, in milliseconds
+func (h *latencyHistogram) quantile(q float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	keys := make([]int, 0, len(h.buckets))
+	for k := range h.buckets {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	target := int64(math.Ceil(q * float64(h.count)))
+	var seen int64
+	for _, k := range keys {
+		seen += h.buckets[k]
+		if seen >= target {
+			upper := float64(time.Microsecond) * math.Pow(latencyGrowth, float64(k))
+			return math.Min(upper, float64(h.max)) / float64(time.Millisecond)
+		}
+	}
+	return float64(h.max) / float64(time.Millisecond)
+}
+
+// CountItems returns the number of resources and items in an export request. Items are
+// data points for metrics, spans for traces and log records for logs.
+func CountItems(req proto.Message) (int, int) {
+	items := 0
+	switch r := req.(type) {
+	case *collectorpb.ExportMetricsServiceRequest:
+		for _, rm := range r.ResourceMetrics {
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					items += len(m.GetGauge().GetDataPoints()) + len(m.GetSum().GetDataPoints()) +
+						len(m.GetHistogram().GetDataPoints()) + len(m.GetExponentialHistogram().GetDataPoints()) +
+						len(m.GetSummary().GetDataPoints())
+				}
+			}
+		}
+		return len(r.ResourceMetrics), items
+	case *tracecollectorpb.ExportTraceServiceRequest:
+		for _, rs := range r.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				items += len(ss.Spans)
+			}
+		}
+		return len(r.ResourceSpans), items
+	case *logscollectorpb.ExportLogsServiceRequest:
+		for _, rl := range r.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				items += len(sl.LogRecords)
+			}
+		}
+		return len(r.ResourceLogs), items
+	}
+	return 0, 0
+}
+
+// seriesHashes identifies every metric time series of a metrics request by its resource
+// attributes, metric name and data point attributes
+func seriesHashes(req proto.Message) []uint64 {
+	metricsReq, ok := req.(*collectorpb.ExportMetricsServiceRequest)
+	if !ok {
+		return nil
+	}
+	var hashes []uint64
+	for _, rm := range metricsReq.ResourceMetrics {
+		resource := attributesKey(rm.GetResource().GetAttributes())
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				for _, attrs := range dataPointAttributes(m) {
+					h := fnv.New64a()
+					h.Write([]byte(resource))
+					h.Write([]byte{0})
+					h.Write([]byte(m.Name))
+					h.Write([]byte{0})
+					h.Write([]byte(attributesKey(attrs)))
+					hashes = append(hashes, h.Sum64())
+				}
+			}
+		}
+	}
+	return hashes
+}
+
+func dataPointAttributes(m *metricpb.Metric) [][]*commonpb.KeyValue {
+	var attrs [][]*commonpb.KeyValue
+	for _, dp := range m.GetGauge().GetDataPoints() {
+		attrs = append(attrs, dp.Attributes)
+	}
+	for _, dp := range m.GetSum().GetDataPoints() {
+		attrs = append(attrs, dp.Attributes)
+	}
+	for _, dp := range m.GetHistogram().GetDataPoints() {
+		attrs = append(attrs, dp.Attributes)
+	}
+	for _, dp := range m.GetExponentialHistogram().GetDataPoints() {
+		attrs = append(attrs, dp.Attributes)
+	}
+	for _, dp := range m.GetSummary().GetDataPoints() {
+		attrs = append(attrs, dp.Attributes)
+	}
+	return attrs
+}
+
+// attributesKey renders attributes independent of their order
+func attributesKey(attrs []*commonpb.KeyValue) string {
+	parts := make([]string, 0, len(attrs))
+	for _, kv := range attrs {
+		parts = append(parts, kv.Key+"="+kv.GetValue().String())
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\x00")
+}
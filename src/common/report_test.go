@@ -0,0 +1,101 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// flakySink fails with a retryable error until it has been called failures times
+type flakySink struct {
+	failures int
+	calls    int
+}
+
+func (s *flakySink) Export(ctx context.Context, _ proto.Message) error {
+	s.calls++
+	recordAttempt(ctx, 100)
+	if s.calls <= s.failures {
+		return &ExportError{StatusCode: 503, Retryable: true, Err: errors.New("unavailable")}
+	}
+	return nil
+}
+
+func (s *flakySink) Close() error { return nil }
+
+// withReport runs the test against a fresh report and returns its final summary by endpoint
+func withReport(t *testing.T, send func()) map[string]EndpointSummary {
+	t.Helper()
+	old := Report
+	t.Cleanup(func() { Report = old })
+	now := time.Now()
+	Report = &LoadReport{total: newReportWindow(now), window: newReportWindow(now), series: make(map[uint64]struct{})}
+
+	send()
+	endpoints := make(map[string]EndpointSummary)
+	for _, e := range Report.summarize(Report.total, time.Now(), true).Endpoints {
+		endpoints[e.Endpoint] = e
+	}
+	return endpoints
+}
+
+func TestReportCountsRetriesSeparately(t *testing.T) {
+	policy := RetryPolicy{Enabled: true, MaxAttempts: 5, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	endpoints := withReport(t, func() {
+		sink := &reportingSink{name: "gw", next: newRetryingExporter(&flakySink{failures: 2}, policy)}
+		if err := sink.Export(context.Background(), testMetricsRequest("retried")); err != nil {
+			t.Fatal(err)
+		}
+		sink = &reportingSink{name: "down", next: newRetryingExporter(&flakySink{failures: 10}, policy)}
+		if err := sink.Export(context.Background(), testMetricsRequest("failed")); err == nil {
+			t.Fatal("export succeeded although every attempt failed")
+		}
+	})
+
+	for name, want := range map[string]EndpointSummary{
+		"gw":   {Requests: 1, Retries: 2, Failed: 0, Items: 1, Bytes: 100},
+		"down": {Requests: 1, Retries: 4, Failed: 1, Items: 0, Bytes: 100},
+	} {
+		got := endpoints[name]
+		if got.Requests != want.Requests || got.Retries != want.Retries || got.Failed != want.Failed || got.Items != want.Items || got.Bytes != want.Bytes {
+			t.Errorf("%s: %d requests, %d retries, %d failed, %d items, %d bytes; want %d, %d, %d, %d, %d", name,
+				got.Requests, got.Retries, got.Failed, got.Items, got.Bytes, want.Requests, want.Retries, want.Failed, want.Items, want.Bytes)
+		}
+	}
+	if errs := endpoints["down"].Errors; errs["503"] != 1 {
+		t.Errorf("down: errors = %v, want one 503", errs)
+	}
+}
+
+func TestReportCountsLocalSinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otlp.ndjson")
+	oldSinks := Sinks
+	t.Cleanup(func() { Sinks = oldSinks })
+	Sinks = []SinkConfig{{Type: SinkFile, Path: path}, {Type: SinkNull}}
+
+	req := testMetricsRequest("local")
+	line, _ := marshalJSONLine(req)
+	endpoints := withReport(t, func() {
+		sink, err := NewSink()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sink.Close()
+		for i := 0; i < 3; i++ {
+			if err := sink.Export(context.Background(), req); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	for name, wantBytes := range map[string]int64{path: int64(3 * len(line)), SinkNull: int64(3 * proto.Size(req))} {
+		got := endpoints[name]
+		if got.Requests != 3 || got.Items != 3 || got.Bytes != wantBytes || got.Signal != "metrics" {
+			t.Errorf("%s: %+v, want 3 metrics requests with 3 items and %d bytes", name, got, wantBytes)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	logscollectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	tracecollectorpb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// RetryPolicy controls how failed exports are retried
+type RetryPolicy struct {
+	Enabled        bool          `yaml:"enabled"`
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	Jitter         float64       `yaml:"jitter"`
+	MaxElapsedTime time.Duration `yaml:"max_elapsed_time"`
+}
+
+// DefaultRetryPolicy mirrors the defaults of the OpenTelemetry Collector exporters
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Enabled:        true,
+		MaxAttempts:    5,
+		InitialBackoff: 1 * time.Second,
+		MaxBackoff:     30 * time.Second,
+		Jitter:         0.2,
+		MaxElapsedTime: 60 * time.Second,
+	}
+}
+
+// Validate reports the first invalid setting of the policy
+func (p RetryPolicy) Validate() error {
+	switch {
+	case p.MaxAttempts <= 0:
+		return fmt.Errorf("retry.max_attempts must be > 0, got %d", p.MaxAttempts)
+	case p.InitialBackoff <= 0:
+		return fmt.Errorf("retry.initial_backoff must be > 0, got %s", p.InitialBackoff)
+	case p.MaxBackoff < p.InitialBackoff:
+		return fmt.Errorf("retry.max_backoff (%s) must be >= retry.initial_backoff (%s)", p.MaxBackoff, p.InitialBackoff)
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("retry.jitter must be between 0 and 1, got %g", p.Jitter)
+	case p.MaxElapsedTime < 0:
+		return fmt.Errorf("retry.max_elapsed_time must be >= 0, got %s", p.MaxElapsedTime)
+	}
+	return nil
+}
+
+// backoff returns the jittered exponential delay before the given retry (1-based)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(p.InitialBackoff) * math.Pow(2, float64(retry-1))
+	if delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay)
+}
+
+// ExportError describes a failed export and whether the OTLP spec allows retrying it
+type ExportError struct {
+	StatusCode int        // HTTP status code, 0 if the request never got a response
+	GRPCCode   codes.Code // gRPC status code, codes.OK for HTTP exports
+	Retryable  bool
+	RetryAfter time.Duration // Server-requested delay, 0 if none was given
+	Err        error
+}
+
+func (e *ExportError) Error() string { return e.Err.Error() }
+func (e *ExportError) Unwrap() error { return e.Err }
+
+// isRetryableHTTPStatus follows the OTLP/HTTP spec: only 429, 502, 503 and 504 may be retried
+func isRetryableHTTPStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter accepts both forms of the Retry-After header: delay-seconds and HTTP-date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// newGRPCExportError classifies a gRPC error per the OTLP/gRPC spec
+func newGRPCExportError(err error) *ExportError {
+	st := status.Convert(err)
+	exportErr := &ExportError{GRPCCode: st.Code(), Err: err}
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			exportErr.RetryAfter = info.GetRetryDelay().AsDuration()
+		}
+	}
+
+	switch st.Code() {
+	case codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+		exportErr.Retryable = true
+	case codes.ResourceExhausted:
+		// Only retryable when the server signals it can recover via RetryInfo
+		exportErr.Retryable = exportErr.RetryAfter > 0
+	}
+	return exportErr
+}
+
+// reportPartialSuccess logs items the collector accepted the request for but rejected
+func reportPartialSuccess(target string, resp proto.Message) {
+	var (
+		rejected int64
+		message  string
+		items    string
+	)
+	switch r := resp.(type) {
+	case *collectorpb.ExportMetricsServiceResponse:
+		rejected, message, items = r.GetPartialSuccess().GetRejectedDataPoints(), r.GetPartialSuccess().GetErrorMessage(), "data points"
+	case *tracecollectorpb.ExportTraceServiceResponse:
+		rejected, message, items = r.GetPartialSuccess().GetRejectedSpans(), r.GetPartialSuccess().GetErrorMessage(), "spans"
+	case *logscollectorpb.ExportLogsServiceResponse:
+		rejected, message, items = r.GetPartialSuccess().GetRejectedLogRecords(), r.GetPartialSuccess().GetErrorMessage(), "log records"
+	default:
+		return
+	}
+
+	if rejected > 0 {
+		log.Printf("⚠️ %s rejected %d %s: %s", target, rejected, items, message)
+	} else if message != "" {
+		log.Printf("⚠️ %s accepted the request with a warning: %s", target, message)
+	}
+}
+
+// retryingExporter retries retryable export errors according to a RetryPolicy
+type retryingExporter struct {
+	next   Sink
+	policy RetryPolicy
+}
+
+func newRetryingExporter(next Sink, policy RetryPolicy) *retryingExporter {
+	return &retryingExporter{next: next, policy: policy}
+}
+
+func (e *retryingExporter) Export(ctx context.Context, otlpRequest proto.Message) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := e.next.Export(ctx, otlpRequest)
+		if err == nil {
+			return nil
+		}
+
+		var exportErr *ExportError
+		if !errors.As(err, &exportErr) || !exportErr.Retryable {
+			return err
+		}
+		if attempt >= e.policy.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		delay := e.policy.backoff(attempt)
+		if exportErr.RetryAfter > 0 {
+			delay = exportErr.RetryAfter
+		}
+		if e.policy.MaxElapsedTime > 0 && time.Since(start)+delay > e.policy.MaxElapsedTime {
+			return fmt.Errorf("giving up after %d attempts, next retry would exceed %s: %w", attempt, e.policy.MaxElapsedTime, err)
+		}
+
+		log.Printf("🔁 Retrying export in %s (attempt %d/%d): %v", delay.Round(time.Millisecond), attempt+1, e.policy.MaxAttempts, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("export cancelled while waiting to retry: %w", err)
+		case <-timer.C:
+		}
+	}
+}
+
+func (e *retryingExporter) Close() error {
+	return e.next.Close()
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIsRetryableHTTPStatus(t *testing.T) {
+	for code, want := range map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusBadRequest:          false,
+		http.StatusUnauthorized:        false,
+		http.StatusRequestTimeout:      false,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: false,
+		http.StatusBadGateway:          true,
+		http.StatusServiceUnavailable:  true,
+		http.StatusGatewayTimeout:      true,
+	} {
+		if got := isRetryableHTTPStatus(code); got != want {
+			t.Errorf("isRetryableHTTPStatus(%d) = %t, want %t", code, got, want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"":                              0,
+		"5":                             5 * time.Second,
+		" 120 ":                         2 * time.Minute,
+		"0":                             0,
+		"-3":                            0,
+		"soon":                          0,
+		"1.5":                           0,
+		"Wed, 21 Oct 2015 07:28:00 GMT": 0, // In the past
+	} {
+		if got := parseRetryAfter(value); got != want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", value, got, want)
+		}
+	}
+
+	future := time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got < 80*time.Second || got > 90*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %s, want about 90s", future, got)
+	}
+}
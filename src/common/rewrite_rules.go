@@ -0,0 +1,127 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Attribute levels a rewrite rule can apply to
+const (
+	RuleLevelResource  = "resource"
+	RuleLevelDataPoint = "datapoint"
+)
+
+// RewriteRule is one entry of the rewrite_rules list in config.yaml. It matches string
+// attributes by exact key or key regex and replaces their value with the rendered template.
+type RewriteRule struct {
+	Key      string `yaml:"key"`
+	KeyRegex string `yaml:"key_regex"`
+	Level    string `yaml:"level"` // resource (default) or datapoint
+	Value    string `yaml:"value"` // text/template, see RewriteContext
+
+	keyRegex *regexp.Regexp
+	value    *template.Template
+}
+
+// DefaultRewriteRules reproduce the identity rewriting used before rules were configurable
+func DefaultRewriteRules() []RewriteRule {
+	return []RewriteRule{
+		{Key: "k8s.cluster.name", Value: `{{.Cluster}}-{{printf "%02d" .Replica}}`},
+		{Key: "k8s.node.name", Value: `{{.Value}}-{{letters .Node}}-{{printf "%02d" .Replica}}`},
+		{Key: "host.name", Value: `{{.Ref "k8s.node.name"}}`},
+		{Key: "k8s.pod.uid", Value: `uid-{{trunc 8 .Value}}-{{printf "%02d" .Replica}}`},
+	}
+}
+
+// rewriteFuncs are the helper functions available in rule templates
+var rewriteFuncs = template.FuncMap{
+	// letters turns an index into the AA, AB, ... suffix used for repeated node names
+	"letters": func(i int) string { return fmt.Sprintf("%c%c", 'A'+i/26, 'A'+i%26) },
+	"trunc": func(n int, s string) string {
+		if len(s) > n {
+			return s[:n]
+		}
+		return s
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// Compile validates the rule and prepares its key regex and template
+func (r *RewriteRule) Compile() error {
+	if (r.Key == "") == (r.KeyRegex == "") {
+		return fmt.Errorf("exactly one of key and key_regex must be set")
+	}
+	name := r.Key
+	if r.KeyRegex != "" {
+		name = r.KeyRegex
+		re, err := regexp.Compile(r.KeyRegex)
+		if err != nil {
+			return fmt.Errorf("%s: invalid key_regex: %v", name, err)
+		}
+		r.keyRegex = re
+	}
+	switch r.Level {
+	case "":
+		r.Level = RuleLevelResource
+	case RuleLevelResource, RuleLevelDataPoint:
+	default:
+		return fmt.Errorf("%s: unknown level %q (must be %s or %s)", name, r.Level, RuleLevelResource, RuleLevelDataPoint)
+	}
+	tmpl, err := template.New(name).Funcs(rewriteFuncs).Option("missingkey=error").Parse(r.Value)
+	if err != nil {
+		return fmt.Errorf("%s: invalid value template: %v", name, err)
+	}
+	r.value = tmpl
+	return nil
+}
+
+// Matches reports whether the rule applies to the attribute key
+func (r *RewriteRule) Matches(key string) bool {
+	if r.keyRegex != nil {
+		return r.keyRegex.MatchString(key)
+	}
+	return r.Key == key
+}
+
+// RewriteContext is the data a rule template is rendered with
+type RewriteContext struct {
+	Key     string // Attribute key
+	Value   string // Original value
+	Replica int    // Replica (cluster) index
+	Node    int    // Index of the resource's node within the replica, for repeated node names
+	Cluster string // base_cluster from config.yaml
+	Base    string // base_name from config.yaml
+
+	attrs []Attribute
+}
+
+// Hash returns the hex SHA-256 of the original value and replica index; use trunc to shorten it
+func (c RewriteContext) Hash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%d", c.Value, c.Replica)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Ref returns the current value of another attribute at the same level, including
+// rewrites of earlier rules, or "" if it is missing
+func (c RewriteContext) Ref(key string) string {
+	for _, attr := range c.attrs {
+		if attr.Key == key {
+			return attr.Value.StringValue
+		}
+	}
+	return ""
+}
+
+// Render executes the rule template
+func (r *RewriteRule) Render(ctx RewriteContext) (string, error) {
+	var out strings.Builder
+	if err := r.value.Execute(&out, ctx); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
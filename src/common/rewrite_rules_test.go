@@ -0,0 +1,127 @@
+package common
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func compiledRules(t *testing.T, rules ...RewriteRule) []RewriteRule {
+	t.Helper()
+	for i := range rules {
+		if err := rules[i].Compile(); err != nil {
+			t.Fatalf("rule %d: %v", i, err)
+		}
+	}
+	return rules
+}
+
+func TestRewriteRuleRender(t *testing.T) {
+	ctx := RewriteContext{Key: "k8s.node.name", Value: "Node-A", Replica: 3, Node: 27, Cluster: "demo", Base: "demo-node"}
+	for _, tt := range []struct {
+		template string
+		want     string
+	}{
+		{`{{.Cluster}}-{{printf "%02d" .Replica}}`, "demo-03"},
+		{`{{.Value}}-{{letters .Node}}`, "Node-A-BB"},
+		{`{{letters 0}}{{letters 25}}`, "AAAZ"},
+		{`{{.Base}}/{{.Key}}`, "demo-node/k8s.node.name"},
+		{`{{lower .Value}} {{upper .Value}} {{trunc 3 .Value}} {{trunc 10 .Value}}`, "node-a NODE-A Nod Node-A"},
+		{`{{trunc 8 .Hash}}`, ctx.Hash()[:8]},
+	} {
+		rule := compiledRules(t, RewriteRule{Key: "k8s.node.name", Value: tt.template})[0]
+		if got, err := rule.Render(ctx); err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v; want %q", tt.template, got, err, tt.want)
+		}
+	}
+
+	other := ctx
+	other.Replica = 4
+	if ctx.Hash() == other.Hash() || ctx.Hash() != (RewriteContext{Value: "Node-A", Replica: 3}).Hash() {
+		t.Error("Hash must depend on the value and replica only")
+	}
+}
+
+func TestRewriteRuleCompile(t *testing.T) {
+	for _, tt := range []struct {
+		rule    RewriteRule
+		problem string // Empty when the rule is valid
+	}{
+		{RewriteRule{Key: "host.name", Value: "{{.Value}}"}, ""},
+		{RewriteRule{KeyRegex: `^k8s\.pod\..*`, Level: RuleLevelDataPoint, Value: "x"}, ""},
+		{RewriteRule{Value: "x"}, "exactly one of key and key_regex"},
+		{RewriteRule{Key: "a", KeyRegex: "b", Value: "x"}, "exactly one of key and key_regex"},
+		{RewriteRule{KeyRegex: "(", Value: "x"}, "invalid key_regex"},
+		{RewriteRule{Key: "a", Level: "scope", Value: "x"}, "unknown level"},
+		{RewriteRule{Key: "a", Value: "{{.Value"}, "invalid value template"},
+		{RewriteRule{Key: "a", Value: "{{nope .Value}}"}, "invalid value template"},
+	} {
+		err := tt.rule.Compile()
+		switch {
+		case tt.problem == "" && err != nil:
+			t.Errorf("%+v: unexpected error %v", tt.rule, err)
+		case tt.problem != "" && (err == nil || !strings.Contains(err.Error(), tt.problem)):
+			t.Errorf("%+v: error %v, want %q", tt.rule, err, tt.problem)
+		}
+	}
+}
+
+// rewriteWith runs an IdentityRewriter with the given rules over one replica's resources
+func rewriteWith(t *testing.T, rules []RewriteRule, replica int, resources ...Resource) []Resource {
+	t.Helper()
+	oldRules, oldCluster := RewriteRules, BaseClusterName
+	t.Cleanup(func() { RewriteRules, BaseClusterName = oldRules, oldCluster })
+	RewriteRules, BaseClusterName = rules, "demo"
+
+	rewriter := NewIdentityRewriter(replica, LoadReplacements(filepath.Join(t.TempDir(), "replacements.json")))
+	for i := range resources {
+		rewriter.Rewrite(&resources[i])
+	}
+	return resources
+}
+
+func TestRewriteRuleOrder(t *testing.T) {
+	node := RewriteRule{Key: "k8s.node.name", Value: `{{.Value}}-{{printf "%02d" .Replica}}`}
+	host := RewriteRule{Key: "host.name", Value: `{{.Ref "k8s.node.name"}}`}
+
+	for _, tt := range []struct {
+		name  string
+		rules []RewriteRule
+		want  string
+	}{
+		{"ref after the rule it refers to sees the rewrite", []RewriteRule{node, host}, "node-a-02"},
+		{"ref before the rule it refers to sees the original", []RewriteRule{host, node}, "node-a"},
+		{"first matching rule wins", []RewriteRule{node, host, {KeyRegex: ".*", Value: "clobbered"}}, "node-a-02"},
+		{"empty result keeps the original", []RewriteRule{{Key: "host.name", Value: `{{.Ref "missing"}}`}}, "ip-10-0-0-1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := rewriteWith(t, compiledRules(t, tt.rules...), 2, testResource("k8s.node.name", "node-a", "host.name", "ip-10-0-0-1"))
+			if got := attribute(resources[0], "host.name"); got != tt.want {
+				t.Errorf("host.name = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRewriteRules(t *testing.T) {
+	resources := rewriteWith(t, compiledRules(t, DefaultRewriteRules()...), 1,
+		testResource("k8s.cluster.name", "prod", "k8s.node.name", "node-a", "host.name", "ip-10-0-0-1"),
+		testResource("k8s.node.name", "node-a", "k8s.pod.uid", "0123456789abcdef"),
+	)
+	for _, tt := range []struct {
+		resource int
+		key      string
+		want     string
+	}{
+		{0, "k8s.cluster.name", "demo-01"},
+		{0, "k8s.node.name", "node-a-AA-01"},
+		{0, "host.name", "node-a-AA-01"},
+		// The second resource repeating node-a is counted as the next node
+		{1, "k8s.node.name", "node-a-AB-01"},
+		{1, "k8s.pod.uid", "uid-01234567-01"},
+	} {
+		if got := attribute(resources[tt.resource], tt.key); got != tt.want {
+			t.Errorf("resource %d %s = %q, want %q", tt.resource, tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a sequence of phases run back to back by metrics_loadgen -scenario
+type Scenario struct {
+	Version int             `yaml:"version"` // Same schema version as config.yaml
+	Phases  []ScenarioPhase `yaml:"phases"`
+}
+
+// ScenarioPhase is one entry of the phases list of a scenario file. Settings it leaves out
+// are taken from config.yaml, not from the previous phase.
+type ScenarioPhase struct {
+	Name         string        `yaml:"name"`
+	InputFile    string        `yaml:"input_file"`    // Capture replayed during the phase
+	Replicas     int           `yaml:"no_replicas"`   // Replicas sent every interval
+	Interval     time.Duration `yaml:"interval"`      // Time between the start of two iterations
+	Duration     time.Duration `yaml:"duration"`      // How long the phase runs
+	RewriteRules []RewriteRule `yaml:"rewrite_rules"` // Replace the rewrite rules of config.yaml
+	Target       string        `yaml:"target"`        // Collector URL, or host:port for gRPC; replaces endpoints
+	Pause        time.Duration `yaml:"pause"`         // Nothing is sent for this long after the phase
+}
+
+// Total returns how long the whole scenario runs, pauses included
+func (s Scenario) Total() time.Duration {
+	var total time.Duration
+	for _, phase := range s.Phases {
+		total += phase.Duration + phase.Pause
+	}
+	return total
+}
+
+// LoadScenario reads and validates a scenario file. Like LoadConfig it reports every
+// problem at once and exits if there is any. With -validate it must be called before
+// LoadConfig, which exits after its own check.
+func LoadScenario(path string) Scenario {
+	scenario := Scenario{Version: ConfigVersion}
+	var problems configProblems
+	defer func() { problems.exitIfAny(path) }()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		problems.add("failed to read scenario file: %v", err)
+		return scenario
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		problems.add("%v", err)
+		return scenario
+	}
+	if len(doc.Content) == 0 {
+		problems.add("the file is empty")
+		return scenario
+	}
+	root := doc.Content[0]
+	checkKnownKeys(root, reflect.TypeOf(scenario), "", &problems)
+	expandEnv(root, &problems)
+	if err := root.Decode(&scenario); err != nil {
+		addDecodeProblems(err, &problems)
+	}
+
+	if scenario.Version != ConfigVersion {
+		problems.add("unsupported scenario version %d (this build reads version %d)", scenario.Version, ConfigVersion)
+	}
+	if len(scenario.Phases) == 0 {
+		problems.add("no phases")
+	}
+	for i := range scenario.Phases {
+		phase := &scenario.Phases[i]
+		if phase.Name == "" {
+			phase.Name = fmt.Sprintf("phase-%d", i+1)
+		}
+		if phase.Duration <= 0 {
+			problems.add("invalid phases[%d] (%s): duration must be > 0, got %s", i, phase.Name, phase.Duration)
+		}
+		if phase.Replicas < 0 || phase.Interval < 0 || phase.Pause < 0 {
+			problems.add("invalid phases[%d] (%s): no_replicas, interval and pause must be >= 0", i, phase.Name)
+		}
+		if phase.InputFile != "" {
+			if expanded, err := ExpandPath(phase.InputFile); err == nil {
+				phase.InputFile = expanded
+			}
+		}
+		for r := range phase.RewriteRules {
+			if err := phase.RewriteRules[r].Compile(); err != nil {
+				problems.add("invalid phases[%d].rewrite_rules[%d]: %v", i, r, err)
+			}
+		}
+	}
+	if validateOnly && len(problems) == 0 {
+		fmt.Printf("✅ %s is valid, %d phases (%s)\n", path, len(scenario.Phases), scenario.Total())
+	}
+	return scenario
+}
+
+// ApplyPhase sets the globals for a phase: its own settings where given, the ones of
+// config.yaml otherwise. It returns the config keys that changed since the previous phase.
+// It must be called while no replica is being generated or sent.
+func ApplyPhase(phase ScenarioPhase) ConfigChanges {
+	next := baseConfig
+	if phase.InputFile != "" {
+		next.InputFile = phase.InputFile
+	}
+	if phase.Replicas > 0 {
+		next.NoReplicas = phase.Replicas
+	}
+	if phase.Interval > 0 {
+		next.Interval = phase.Interval
+	}
+	if len(phase.RewriteRules) > 0 {
+		next.RewriteRules = phase.RewriteRules
+	}
+	if phase.Target != "" {
+		next.Endpoints = nil
+		if next.Protocol == ProtocolGRPC {
+			next.GRPCEndpoint = phase.Target
+		} else {
+			next.CollectorURL = phase.Target
+		}
+	}
+	changes := changedConfigKeys(loadedConfig, next)
+	applyConfig(next)
+	return changes
+}
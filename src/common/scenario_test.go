@@ -0,0 +1,67 @@
+package common
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// withBaseConfig makes cfg the config.yaml phases fall back to, and the one last applied
+func withBaseConfig(t *testing.T, cfg configStruct) {
+	t.Helper()
+	oldBase, oldLoaded := baseConfig, loadedConfig
+	t.Cleanup(func() {
+		baseConfig = oldBase
+		applyConfig(oldLoaded)
+	})
+	baseConfig = cfg
+	applyConfig(cfg)
+}
+
+func TestApplyPhase(t *testing.T) {
+	rules := compiledRules(t, RewriteRule{Key: "host.name", Value: "{{.Value}}-x"})
+	withBaseConfig(t, configStruct{
+		InputFile:    "/captures/base.json",
+		NoReplicas:   2,
+		Interval:     10 * time.Second,
+		Protocol:     ProtocolHTTPJSON,
+		CollectorURL: "http://gw:4318",
+		Endpoints:    []string{"http://gw-a:4318", "http://gw-b:4318"},
+		RewriteRules: compiledRules(t, DefaultRewriteRules()...),
+	})
+
+	// The phases run in order, each compared against the one before it
+	for _, tt := range []struct {
+		phase     ScenarioPhase
+		want      ConfigChanges
+		replicas  int
+		interval  time.Duration
+		collector string
+		endpoints int
+	}{
+		{ScenarioPhase{Name: "baseline"}, nil, 2, 10 * time.Second, "http://gw:4318", 2},
+		{ScenarioPhase{Name: "peak", Replicas: 20, Interval: 5 * time.Second}, ConfigChanges{"no_replicas", "interval"}, 20, 5 * time.Second, "http://gw:4318", 2},
+		// Left out settings come from config.yaml, not from the previous phase
+		{ScenarioPhase{Name: "capture", InputFile: "/captures/peak.json"}, ConfigChanges{"no_replicas", "input_file", "interval"}, 2, 10 * time.Second, "http://gw:4318", 2},
+		{ScenarioPhase{Name: "failover", Target: "http://dr:4318", RewriteRules: rules}, ConfigChanges{"collectorURL", "input_file", "endpoints", "rewrite_rules"}, 2, 10 * time.Second, "http://dr:4318", 0},
+		{ScenarioPhase{Name: "back"}, ConfigChanges{"collectorURL", "endpoints", "rewrite_rules"}, 2, 10 * time.Second, "http://gw:4318", 2},
+	} {
+		changes := ApplyPhase(tt.phase)
+		if !slices.Equal(changes, tt.want) {
+			t.Errorf("%s: changed %v, want %v", tt.phase.Name, changes, tt.want)
+		}
+		if NoReplicas != tt.replicas || Interval != tt.interval || CollectorURL != tt.collector || len(Endpoints) != tt.endpoints {
+			t.Errorf("%s: %d replicas every %s to %s and %d endpoints; want %d, %s, %s, %d", tt.phase.Name,
+				NoReplicas, Interval, CollectorURL, len(Endpoints), tt.replicas, tt.interval, tt.collector, tt.endpoints)
+		}
+	}
+}
+
+func TestApplyPhaseGRPCTarget(t *testing.T) {
+	withBaseConfig(t, configStruct{Protocol: ProtocolGRPC, GRPCEndpoint: "gw:4317", CollectorURL: "http://gw:4318", NoReplicas: 1})
+
+	changes := ApplyPhase(ScenarioPhase{Name: "grpc", Target: "dr:4317"})
+	if !slices.Equal(changes, ConfigChanges{"grpc_endpoint"}) || GRPCEndpoint != "dr:4317" || CollectorURL != "http://gw:4318" {
+		t.Errorf("changed %v, grpc endpoint %s, collector %s; want only the grpc endpoint set to dr:4317", changes, GRPCEndpoint, CollectorURL)
+	}
+}
@@ -0,0 +1,174 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// Sink receives OTLP export requests (metrics, traces or logs). Every binary sends
+// through the sink built from the sinks section of config.yaml.
+type Sink interface {
+	Export(ctx context.Context, req proto.Message) error
+	Close() error
+}
+
+// Supported sink types
+const (
+	SinkOTLP   = "otlp"   // Collector over the configured protocol, with retries
+	SinkFile   = "file"   // OTLP/JSON lines, rotated by size
+	SinkStdout = "stdout" // OTLP/JSON lines on stdout
+	SinkNull   = "null"   // Discards everything, for pure generator benchmarks
+)
+
+// SinkConfig is one entry of the sinks list in config.yaml
+type SinkConfig struct {
+	Type      string `yaml:"type"`
+	Path      string `yaml:"path"`        // file: output file, rotated to path.1, path.2, ...
+	MaxSizeMB int    `yaml:"max_size_mb"` // file: rotate once the file would exceed this size (0 = never)
+	MaxFiles  int    `yaml:"max_files"`   // file: rotated files to keep
+}
+
+// UnmarshalYAML accepts an unquoted type: null, which YAML would otherwise read as no type
+func (c *SinkConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain SinkConfig
+	if err := value.Decode((*plain)(c)); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		if value.Content[i].Value == "type" && strings.EqualFold(value.Content[i+1].Value, "null") {
+			c.Type = SinkNull
+		}
+	}
+	return nil
+}
+
+// Validate checks the settings the sink type needs
+func (c SinkConfig) Validate() error {
+	switch c.Type {
+	case SinkOTLP, SinkStdout, SinkNull:
+	case SinkFile:
+		if c.Path == "" {
+			return fmt.Errorf("file sink needs a path")
+		}
+		if c.MaxSizeMB < 0 || c.MaxFiles < 0 {
+			return fmt.Errorf("file sink max_size_mb and max_files must be >= 0, got %d and %d", c.MaxSizeMB, c.MaxFiles)
+		}
+	default:
+		return fmt.Errorf("unknown sink type %q (must be %s, %s, %s or %s)", c.Type, SinkOTLP, SinkFile, SinkStdout, SinkNull)
+	}
+	return nil
+}
+
+// NewSink creates the sinks configured in config.yaml, combined in a tee if there is
+// more than one. The items handed to it are counted in the self-telemetry.
+func NewSink() (Sink, error) {
+	var sinks []Sink
+	for _, cfg := range Sinks {
+		sink, err := newSink(cfg)
+		if err != nil {
+			for _, created := range sinks {
+				created.Close()
+			}
+			return nil, fmt.Errorf("%s sink: %w", cfg.Type, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 1 {
+		return &telemetrySink{next: sinks[0]}, nil
+	}
+	return &telemetrySink{next: &teeSink{sinks: sinks}}, nil
+}
+
+// newSink creates one sink of the sinks list. Everything but otlp is counted in the
+// report here; otlp counts every collector endpoint on its own.
+func newSink(cfg SinkConfig) (Sink, error) {
+	switch cfg.Type {
+	case SinkOTLP:
+		return newOTLPSink()
+	case SinkFile:
+		path, err := ExpandPath(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		sink, err := newFileSink(path, int64(cfg.MaxSizeMB)<<20, cfg.MaxFiles)
+		if err != nil {
+			return nil, err
+		}
+		return &reportingSink{name: path, next: sink}, nil
+	case SinkStdout:
+		return &reportingSink{name: SinkStdout, next: &writerSink{w: os.Stdout}}, nil
+	case SinkNull:
+		return &reportingSink{name: SinkNull, next: nullSink{}}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+	}
+}
+
+// teeSink sends every request to all of its sinks, even if one of them fails
+type teeSink struct {
+	sinks []Sink
+}
+
+func (t *teeSink) Export(ctx context.Context, req proto.Message) error {
+	var errs []error
+	for _, sink := range t.sinks {
+		if err := sink.Export(ctx, req); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *teeSink) Close() error {
+	var errs []error
+	for _, sink := range t.sinks {
+		errs = append(errs, sink.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// writerSink writes every request as one line of OTLP/JSON
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *writerSink) Export(ctx context.Context, req proto.Message) error {
+	line, err := marshalJSONLine(req)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recordAttempt(ctx, len(line))
+	_, err = s.w.Write(line)
+	return err
+}
+
+func (s *writerSink) Close() error {
+	return nil
+}
+
+type nullSink struct{}
+
+func (nullSink) Export(context.Context, proto.Message) error { return nil }
+
+func (nullSink) Close() error { return nil }
+
+// marshalJSONLine encodes a request as a newline terminated OTLP/JSON line, the format
+// the collector's file exporter writes and its otlpjsonfile receiver reads
+func marshalJSONLine(req proto.Message) ([]byte, error) {
+	body, _, err := MarshalOTLP(req, ProtocolHTTPJSON)
+	if err != nil {
+		return nil, err
+	}
+	return append(body, '\n'), nil
+}
@@ -0,0 +1,100 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// fileSink appends OTLP/JSON lines to a file. Once the file would grow beyond maxSize it is
+// renamed to path.1 (shifting older files up to path.<maxFiles>) and a new one is started.
+type fileSink struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func newFileSink(path string, maxSize int64, maxFiles int) (*fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	sink := &fileSink{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file, s.size = file, info.Size()
+	return nil
+}
+
+func (s *fileSink) Export(ctx context.Context, req proto.Message) error {
+	line, err := marshalJSONLine(req)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return fmt.Errorf("rotating %s: %w", s.path, err)
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	recordAttempt(ctx, n)
+	return err
+}
+
+// rotate closes the current file and shifts it and its predecessors one number up,
+// dropping the oldest one beyond maxFiles. If that fails the current file is reopened, so
+// later exports keep working and try again.
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return errors.Join(err, s.open())
+	}
+	if err := s.shift(); err != nil {
+		return errors.Join(err, s.open())
+	}
+	log.Printf("🔁 Rotated output file %s", s.path)
+	return s.open()
+}
+
+// shift renames the closed file to path.1, moving the older files up
+func (s *fileSink) shift() error {
+	if s.maxFiles == 0 {
+		return os.Remove(s.path)
+	}
+	_ = os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxFiles))
+	for i := s.maxFiles - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+	}
+	return os.Rename(s.path, s.path+".1")
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
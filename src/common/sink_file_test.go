@@ -0,0 +1,86 @@
+package common
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+func testMetricsRequest(name string) *collectorpb.ExportMetricsServiceRequest {
+	return &collectorpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricpb.ResourceMetrics{{
+			ScopeMetrics: []*metricpb.ScopeMetrics{{
+				Metrics: []*metricpb.Metric{{
+					Name: name,
+					Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{
+						DataPoints: []*metricpb.NumberDataPoint{{Value: &metricpb.NumberDataPoint_AsDouble{AsDouble: 1}}},
+					}},
+				}},
+			}},
+		}},
+	}
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.ndjson")
+	sink, err := newFileSink(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	for _, name := range []string{"first", "second", "third"} {
+		if err := sink.Export(context.Background(), testMetricsRequest(name)); err != nil {
+			t.Fatalf("export %s: %v", name, err)
+		}
+	}
+	for file, want := range map[string]string{path: "third", path + ".1": "second", path + ".2": "first"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), want) || strings.Count(string(data), "\n") != 1 {
+			t.Errorf("%s = %q, want the %s request only", file, data, want)
+		}
+	}
+}
+
+func TestFileSinkRecoversFromFailedRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.ndjson")
+	sink, err := newFileSink(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err := sink.Export(context.Background(), testMetricsRequest("first")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A non-empty directory where the rotated file goes makes the rename fail
+	blocker := filepath.Join(path+".1", "blocker")
+	if err := os.MkdirAll(blocker, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Export(context.Background(), testMetricsRequest("second")); err == nil {
+		t.Fatal("export succeeded although the rotation failed")
+	}
+
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Export(context.Background(), testMetricsRequest("third")); err != nil {
+		t.Fatalf("export after the failed rotation: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "third") {
+		t.Errorf("%s = %q, want the third request", path, data)
+	}
+}
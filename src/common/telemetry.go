@@ -0,0 +1,408 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// TelemetryConfig is the telemetry section of config.yaml: the loadgen's own metrics
+type TelemetryConfig struct {
+	Listen       string        `yaml:"listen"`        // Serves Prometheus text on http://<listen>/metrics ("" = off)
+	OTLPEndpoint string        `yaml:"otlp_endpoint"` // Optional: also pushed as OTLP/HTTP JSON to this base URL
+	PushInterval time.Duration `yaml:"push_interval"` // Time between two pushes
+	// The push never uses the collector's headers, auth or tls, which may be meant for
+	// another host; it only sends these
+	Headers []HeaderConfig `yaml:"headers"`
+	TLS     TLSConfig      `yaml:"tls"`
+}
+
+// Validate checks the push interval when pushing is enabled, and the push headers and TLS
+func (c TelemetryConfig) Validate() error {
+	if c.OTLPEndpoint != "" && c.PushInterval <= 0 {
+		return fmt.Errorf("push_interval must be > 0 when otlp_endpoint is set, got %s", c.PushInterval)
+	}
+	for i, header := range c.Headers {
+		if err := header.Validate(); err != nil {
+			return fmt.Errorf("headers[%d]: %w", i, err)
+		}
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	return nil
+}
+
+// senderSettings resolves the headers and certificates of the push
+func (c TelemetryConfig) senderSettings() (senderSettings, error) {
+	headers, err := resolveHeaders(c.Headers, AuthConfig{})
+	if err != nil {
+		return senderSettings{}, err
+	}
+	tlsConfig, err := c.TLS.Build()
+	if err != nil {
+		return senderSettings{}, err
+	}
+	return senderSettings{headers: headers, tls: tlsConfig}, nil
+}
+
+// Enabled reports whether the metrics are served or pushed anywhere
+func (c TelemetryConfig) Enabled() bool {
+	return c.Listen != "" || c.OTLPEndpoint != ""
+}
+
+// Telemetry holds the loadgen's own metrics; nil unless StartTelemetry enabled them.
+// All of its methods are safe to call on nil.
+var Telemetry *SelfTelemetry
+
+// latencyBounds are the upper bounds, in seconds, of the send latency histogram buckets
+var latencyBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// SelfTelemetry counts what the senders do, by signal and endpoint
+type SelfTelemetry struct {
+	start    time.Time
+	inFlight atomic.Int64
+
+	mu        sync.Mutex
+	pools     []*ReplicaPool
+	generated map[string]int64 // Items handed to the sinks, by signal
+	endpoints map[telemetryKey]*endpointTelemetry
+}
+
+type telemetryKey struct {
+	signal   string
+	endpoint string
+}
+
+type endpointTelemetry struct {
+	requests   int64
+	bytes      int64
+	errors     map[string]int64 // By HTTP status, gRPC code or "transport"
+	buckets    []int64          // Per latencyBounds, plus +Inf; not cumulative
+	latencySum float64
+}
+
+// StartTelemetry serves the metrics on TelemetrySettings.Listen and pushes them to
+// TelemetrySettings.OTLPEndpoint. It does nothing if neither is configured. Call it before
+// creating the replica pool so the queue depth is reported.
+func StartTelemetry() {
+	if !TelemetrySettings.Enabled() {
+		return
+	}
+	Telemetry = &SelfTelemetry{
+		start:     time.Now(),
+		generated: make(map[string]int64),
+		endpoints: make(map[telemetryKey]*endpointTelemetry),
+	}
+
+	if TelemetrySettings.Listen != "" {
+		listener, err := net.Listen("tcp", TelemetrySettings.Listen)
+		if err != nil {
+			log.Fatalf("❌ Failed to listen for telemetry on %s: %v", TelemetrySettings.Listen, err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+			Telemetry.writePrometheus(w)
+		})
+		log.Printf("📡 Serving self-telemetry on http://%s/metrics", listener.Addr())
+		go func() {
+			log.Fatal(http.Serve(listener, mux))
+		}()
+	}
+
+	if TelemetrySettings.OTLPEndpoint != "" {
+		settings, err := TelemetrySettings.senderSettings()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to set up the self-telemetry push: %v\n", err)
+			os.Exit(1)
+		}
+		exporter := newHTTPExporter(TelemetrySettings.OTLPEndpoint, ProtocolHTTPJSON, CompressionNone, ExportTimeout, settings)
+		exporter.untracked = true
+		log.Printf("📡 Pushing self-telemetry to %s every %s", TelemetrySettings.OTLPEndpoint, TelemetrySettings.PushInterval)
+		go func() {
+			for range time.Tick(TelemetrySettings.PushInterval) {
+				if err := exporter.Export(context.Background(), Telemetry.otlpRequest()); err != nil {
+					log.Printf("⚠️ Failed to push self-telemetry: %v", err)
+				}
+			}
+		}()
+	}
+}
+
+// watchPool adds the jobs waiting in the pool's queues to the queue depth
+func (t *SelfTelemetry) watchPool(p *ReplicaPool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pools = append(t.pools, p)
+}
+
+// generate counts the items of a request handed to the sinks
+func (t *SelfTelemetry) generate(req proto.Message) {
+	if t == nil {
+		return
+	}
+	signal, err := signalOf(req)
+	if err != nil {
+		return
+	}
+	_, items := CountItems(req)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.generated[signal.name] += int64(items)
+}
+
+// requestStarted and requestDone bracket one export attempt on the network
+func (t *SelfTelemetry) requestStarted() {
+	if t != nil {
+		t.inFlight.Add(1)
+	}
+}
+
+func (t *SelfTelemetry) requestDone() {
+	if t != nil {
+		t.inFlight.Add(-1)
+	}
+}
+
+// record counts one export attempt, like LoadReport.Record
+func (t *SelfTelemetry) record(endpoint string, req proto.Message, bytes int, latency time.Duration, err error) {
+	if t == nil {
+		return
+	}
+	signal, _ := signalOf(req)
+	key := telemetryKey{signal: signal.name, endpoint: endpoint}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.endpoints[key]
+	if !ok {
+		e = &endpointTelemetry{errors: make(map[string]int64), buckets: make([]int64, len(latencyBounds)+1)}
+		t.endpoints[key] = e
+	}
+	e.requests++
+	e.bytes += int64(bytes)
+	if err != nil {
+		e.errors[errorStatus(err)]++
+	}
+	seconds := latency.Seconds()
+	e.buckets[sort.SearchFloat64s(latencyBounds, seconds)]++
+	e.latencySum += seconds
+}
+
+// queueDepth is the number of replica jobs waiting in all watched pools; t.mu must be held
+func (t *SelfTelemetry) queueDepth() int64 {
+	depth := 0
+	for _, p := range t.pools {
+		depth += p.Pending()
+	}
+	return int64(depth)
+}
+
+// sortedKeys returns the endpoint keys in a stable order; t.mu must be held
+func (t *SelfTelemetry) sortedKeys() []telemetryKey {
+	keys := make([]telemetryKey, 0, len(t.endpoints))
+	for key := range t.endpoints {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].signal != keys[j].signal {
+			return keys[i].signal < keys[j].signal
+		}
+		return keys[i].endpoint < keys[j].endpoint
+	})
+	return keys
+}
+
+func sortedStatuses(errs map[string]int64) []string {
+	statuses := make([]string, 0, len(errs))
+	for status := range errs {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	return statuses
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promLabels renders name/value pairs as a Prometheus label set
+func promLabels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pairs[i], labelEscaper.Replace(pairs[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// writePrometheus writes all metrics in the Prometheus text exposition format
+func (t *SelfTelemetry) writePrometheus(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := t.sortedKeys()
+
+	header := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	header("loadgen_requests_in_flight", "gauge", "Export requests waiting for a response.")
+	fmt.Fprintf(w, "loadgen_requests_in_flight %d\n", t.inFlight.Load())
+
+	header("loadgen_queue_depth", "gauge", "Replica jobs waiting for a worker.")
+	fmt.Fprintf(w, "loadgen_queue_depth %d\n", t.queueDepth())
+
+	header("loadgen_generated_items_total", "counter", "Data points, spans or log records handed to the sinks.")
+	signals := make([]string, 0, len(t.generated))
+	for signal := range t.generated {
+		signals = append(signals, signal)
+	}
+	sort.Strings(signals)
+	for _, signal := range signals {
+		fmt.Fprintf(w, "loadgen_generated_items_total%s %d\n", promLabels("signal", signal), t.generated[signal])
+	}
+
+	header("loadgen_requests_total", "counter", "Export attempts, including retries.")
+	for _, key := range keys {
+		fmt.Fprintf(w, "loadgen_requests_total%s %d\n", promLabels("signal", key.signal, "endpoint", key.endpoint), t.endpoints[key].requests)
+	}
+
+	header("loadgen_sent_bytes_total", "counter", "Request bytes sent, after compression for HTTP.")
+	for _, key := range keys {
+		fmt.Fprintf(w, "loadgen_sent_bytes_total%s %d\n", promLabels("signal", key.signal, "endpoint", key.endpoint), t.endpoints[key].bytes)
+	}
+
+	header("loadgen_request_errors_total", "counter", "Failed export attempts by HTTP status, gRPC code or transport.")
+	for _, key := range keys {
+		e := t.endpoints[key]
+		for _, status := range sortedStatuses(e.errors) {
+			fmt.Fprintf(w, "loadgen_request_errors_total%s %d\n",
+				promLabels("signal", key.signal, "endpoint", key.endpoint, "code", status), e.errors[status])
+		}
+	}
+
+	header("loadgen_request_duration_seconds", "histogram", "Latency of export attempts.")
+	for _, key := range keys {
+		e := t.endpoints[key]
+		var cumulative int64
+		for i, count := range e.buckets {
+			cumulative += count
+			le := "+Inf"
+			if i < len(latencyBounds) {
+				le = fmt.Sprintf("%g", latencyBounds[i])
+			}
+			fmt.Fprintf(w, "loadgen_request_duration_seconds_bucket%s %d\n",
+				promLabels("signal", key.signal, "endpoint", key.endpoint, "le", le), cumulative)
+		}
+		labels := promLabels("signal", key.signal, "endpoint", key.endpoint)
+		fmt.Fprintf(w, "loadgen_request_duration_seconds_sum%s %g\n", labels, e.latencySum)
+		fmt.Fprintf(w, "loadgen_request_duration_seconds_count%s %d\n", labels, e.requests)
+	}
+}
+
+// otlpRequest converts the same metrics to an OTLP request with cumulative temporality
+func (t *SelfTelemetry) otlpRequest() *collectorpb.ExportMetricsServiceRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	start, now := uint64(t.start.UnixNano()), uint64(time.Now().UnixNano())
+
+	gauge := func(name, description string, value int64) *metricpb.Metric {
+		return &metricpb.Metric{Name: name, Description: description, Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{
+			DataPoints: []*metricpb.NumberDataPoint{{TimeUnixNano: now, Value: &metricpb.NumberDataPoint_AsInt{AsInt: value}}},
+		}}}
+	}
+	counter := func(name, description, unit string) (*metricpb.Metric, *metricpb.Sum) {
+		sum := &metricpb.Sum{AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, IsMonotonic: true}
+		return &metricpb.Metric{Name: name, Description: description, Unit: unit, Data: &metricpb.Metric_Sum{Sum: sum}}, sum
+	}
+	point := func(value int64, pairs ...string) *metricpb.NumberDataPoint {
+		return &metricpb.NumberDataPoint{StartTimeUnixNano: start, TimeUnixNano: now,
+			Value: &metricpb.NumberDataPoint_AsInt{AsInt: value}, Attributes: stringAttributes(pairs...)}
+	}
+
+	generated, generatedSum := counter("loadgen.generated_items", "Data points, spans or log records handed to the sinks.", "{item}")
+	for signal, items := range t.generated {
+		generatedSum.DataPoints = append(generatedSum.DataPoints, point(items, "signal", signal))
+	}
+	requests, requestsSum := counter("loadgen.requests", "Export attempts, including retries.", "{request}")
+	sent, sentSum := counter("loadgen.sent_bytes", "Request bytes sent, after compression for HTTP.", "By")
+	errs, errsSum := counter("loadgen.request_errors", "Failed export attempts by HTTP status, gRPC code or transport.", "{request}")
+	duration := &metricpb.Histogram{AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE}
+
+	for _, key := range t.sortedKeys() {
+		e := t.endpoints[key]
+		requestsSum.DataPoints = append(requestsSum.DataPoints, point(e.requests, "signal", key.signal, "endpoint", key.endpoint))
+		sentSum.DataPoints = append(sentSum.DataPoints, point(e.bytes, "signal", key.signal, "endpoint", key.endpoint))
+		for _, status := range sortedStatuses(e.errors) {
+			errsSum.DataPoints = append(errsSum.DataPoints, point(e.errors[status], "signal", key.signal, "endpoint", key.endpoint, "code", status))
+		}
+		counts := make([]uint64, len(e.buckets))
+		for i, count := range e.buckets {
+			counts[i] = uint64(count)
+		}
+		sum := e.latencySum
+		duration.DataPoints = append(duration.DataPoints, &metricpb.HistogramDataPoint{
+			StartTimeUnixNano: start,
+			TimeUnixNano:      now,
+			Attributes:        stringAttributes("signal", key.signal, "endpoint", key.endpoint),
+			Count:             uint64(e.requests),
+			Sum:               &sum,
+			BucketCounts:      counts,
+			ExplicitBounds:    latencyBounds,
+		})
+	}
+
+	return &collectorpb.ExportMetricsServiceRequest{ResourceMetrics: []*metricpb.ResourceMetrics{{
+		Resource: &resourcepb.Resource{Attributes: stringAttributes("service.name", filepath.Base(os.Args[0]))},
+		ScopeMetrics: []*metricpb.ScopeMetrics{{
+			Scope: &commonpb.InstrumentationScope{Name: "github.com/hagen-p/o11y-go-loadgen"},
+			Metrics: []*metricpb.Metric{
+				gauge("loadgen.requests_in_flight", "Export requests waiting for a response.", t.inFlight.Load()),
+				gauge("loadgen.queue_depth", "Replica jobs waiting for a worker.", t.queueDepth()),
+				generated, requests, sent, errs,
+				{Name: "loadgen.request_duration", Description: "Latency of export attempts.", Unit: "s",
+					Data: &metricpb.Metric_Histogram{Histogram: duration}},
+			},
+		}},
+	}}}
+}
+
+func stringAttributes(pairs ...string) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		attrs = append(attrs, &commonpb.KeyValue{Key: pairs[i], Value: &commonpb.AnyValue{
+			Value: &commonpb.AnyValue_StringValue{StringValue: pairs[i+1]}}})
+	}
+	return attrs
+}
+
+// telemetrySink counts the items handed to the configured sinks before passing them on
+type telemetrySink struct {
+	next Sink
+}
+
+func (s *telemetrySink) Export(ctx context.Context, req proto.Message) error {
+	Telemetry.generate(req)
+	return s.next.Export(ctx, req)
+}
+
+func (s *telemetrySink) Close() error {
+	return s.next.Close()
+}
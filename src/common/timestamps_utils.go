@@ -33,7 +33,32 @@ func UpdateTimestamps(metricsFile *MetricsFile) {
 				}
 				if metric.Histogram != nil {
 					for i := range metric.Histogram.DataPoints {
-						UpdateInstantaneousHistogramTimestamps(&metric.Histogram.DataPoints[i], now, defaultDiff)
+						if metric.Histogram.AggregationTemporality == 2 {
+							// Cumulative: preserve start time
+							UpdateCumulativeHistogramTimestamps(&metric.Histogram.DataPoints[i], now)
+						} else {
+							UpdateInstantaneousHistogramTimestamps(&metric.Histogram.DataPoints[i], now, defaultDiff)
+						}
+					}
+				}
+				if metric.ExponentialHistogram != nil {
+					for i := range metric.ExponentialHistogram.DataPoints {
+						dp := &metric.ExponentialHistogram.DataPoints[i]
+						oldTime := dp.TimeUnixNano
+						if metric.ExponentialHistogram.AggregationTemporality == 2 {
+							// Cumulative: preserve start time
+							UpdateStringTimestampsKeepStart(&dp.StartTimeUnixNano, &dp.TimeUnixNano, now)
+						} else {
+							UpdateStringTimestamps(&dp.StartTimeUnixNano, &dp.TimeUnixNano, now, defaultDiff)
+						}
+						ShiftExemplarTimestamps(dp.Exemplars, oldTime, dp.TimeUnixNano)
+					}
+				}
+				if metric.Summary != nil {
+					// Summary count and sum are cumulative, so the start time is preserved
+					for i := range metric.Summary.DataPoints {
+						dp := &metric.Summary.DataPoints[i]
+						UpdateStringTimestampsKeepStart(&dp.StartTimeUnixNano, &dp.TimeUnixNano, now)
 					}
 				}
 			}
@@ -42,15 +67,50 @@ func UpdateTimestamps(metricsFile *MetricsFile) {
 }
 
 func UpdateInstantaneousDatapointTimestamps(dp *DataPoint, now int64, fallbackDiff int64) {
+	oldTime := dp.TimeUnixNano
 	UpdateStringTimestamps(&dp.StartTimeUnixNano, &dp.TimeUnixNano, now, fallbackDiff)
+	ShiftExemplarTimestamps(dp.Exemplars, oldTime, dp.TimeUnixNano)
 }
 
 func UpdateInstantaneousHistogramTimestamps(dp *HistogramDataPoint, now int64, fallbackDiff int64) {
+	oldTime := dp.TimeUnixNano
 	UpdateStringTimestamps(&dp.StartTimeUnixNano, &dp.TimeUnixNano, now, fallbackDiff)
+	ShiftExemplarTimestamps(dp.Exemplars, oldTime, dp.TimeUnixNano)
+}
+
+func UpdateCumulativeHistogramTimestamps(dp *HistogramDataPoint, now int64) {
+	oldTime := dp.TimeUnixNano
+	UpdateStringTimestampsKeepStart(&dp.StartTimeUnixNano, &dp.TimeUnixNano, now)
+	ShiftExemplarTimestamps(dp.Exemplars, oldTime, dp.TimeUnixNano)
 }
 
 func UpdateCumulativeDatapointTimestamp(dp *DataPoint, now int64) {
+	oldTime := dp.TimeUnixNano
 	UpdateStringTimestampsKeepStart(&dp.StartTimeUnixNano, &dp.TimeUnixNano, now)
+	ShiftExemplarTimestamps(dp.Exemplars, oldTime, dp.TimeUnixNano)
+}
+
+// ShiftExemplarTimestamps moves exemplars by the same amount their data point moved, so
+// they stay inside the point's time window
+func ShiftExemplarTimestamps(exemplars []Exemplar, oldTime string, newTime string) {
+	if len(exemplars) == 0 {
+		return
+	}
+	oldVal, err1 := strconv.ParseInt(oldTime, 10, 64)
+	newVal, err2 := strconv.ParseInt(newTime, 10, 64)
+	if err1 != nil || err2 != nil {
+		for i := range exemplars {
+			exemplars[i].TimeUnixNano = newTime
+		}
+		return
+	}
+	for i := range exemplars {
+		if ts, err := strconv.ParseInt(exemplars[i].TimeUnixNano, 10, 64); err == nil {
+			exemplars[i].TimeUnixNano = strconv.FormatInt(ts+newVal-oldVal, 10)
+		} else {
+			exemplars[i].TimeUnixNano = newTime
+		}
+	}
 }
 
 func UpdateStringTimestamps(startStr *string, endStr *string, now int64, fallbackDiff int64) {
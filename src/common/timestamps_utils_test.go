@@ -0,0 +1,34 @@
+package common
+
+import "testing"
+
+func TestUpdateTimestampsKeepsCumulativeHistogramStart(t *testing.T) {
+	for _, temporality := range []int{1, 2} {
+		file := &MetricsFile{ResourceMetrics: []ResourceMetric{{
+			ScopeMetrics: []ScopeMetric{{
+				Metrics: []Metric{
+					{Name: "hist", Histogram: &Histogram{
+						AggregationTemporality: temporality,
+						DataPoints:             []HistogramDataPoint{{StartTimeUnixNano: "1000", TimeUnixNano: "2000"}},
+					}},
+					{Name: "exp", ExponentialHistogram: &ExponentialHistogram{
+						AggregationTemporality: temporality,
+						DataPoints:             []ExponentialHistogramDataPoint{{StartTimeUnixNano: "1000", TimeUnixNano: "2000"}},
+					}},
+				},
+			}},
+		}}}
+		UpdateTimestamps(file)
+
+		metrics := file.ResourceMetrics[0].ScopeMetrics[0].Metrics
+		starts := map[string]string{
+			"hist": metrics[0].Histogram.DataPoints[0].StartTimeUnixNano,
+			"exp":  metrics[1].ExponentialHistogram.DataPoints[0].StartTimeUnixNano,
+		}
+		for name, start := range starts {
+			if cumulative := temporality == 2; cumulative != (start == "1000") {
+				t.Errorf("%s with temporality %d: start time %s, want it kept only when cumulative", name, temporality, start)
+			}
+		}
+	}
+}
@@ -11,22 +11,39 @@ type ResourceSpan struct {
 }
 
 type ScopeSpan struct {
-	Scope InstrumentationScope `json:"scope"`
-	Spans []Span               `json:"spans"`
+	Scope     InstrumentationScope `json:"scope"`
+	Spans     []Span               `json:"spans"`
+	SchemaUrl string               `json:"schemaUrl,omitempty"`
 }
 
 type Span struct {
 	TraceID           string      `json:"traceId"`
 	SpanID            string      `json:"spanId"`
+	TraceState        string      `json:"traceState,omitempty"`
 	ParentSpanID      string      `json:"parentSpanId,omitempty"`
 	Name              string      `json:"name"`
 	Kind              int         `json:"kind"`
 	StartTimeUnixNano string      `json:"startTimeUnixNano"`
 	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
 	Attributes        []Attribute `json:"attributes,omitempty"`
+	Events            []SpanEvent `json:"events,omitempty"`
+	Links             []SpanLink  `json:"links,omitempty"`
 	Status            SpanStatus  `json:"status,omitempty"`
 }
 
+type SpanEvent struct {
+	TimeUnixNano string      `json:"timeUnixNano"`
+	Name         string      `json:"name"`
+	Attributes   []Attribute `json:"attributes,omitempty"`
+}
+
+type SpanLink struct {
+	TraceID    string      `json:"traceId"`
+	SpanID     string      `json:"spanId"`
+	TraceState string      `json:"traceState,omitempty"`
+	Attributes []Attribute `json:"attributes,omitempty"`
+}
+
 type SpanStatus struct {
 	Code    int    `json:"code"`
 	Message string `json:"message,omitempty"`
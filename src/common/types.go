@@ -31,3 +31,41 @@ func (i *Int64String) UnmarshalJSON(data []byte) error {
 	*i = Int64String(val)
 	return nil
 }
+
+// MarshalJSON writes the value as a quoted string, as OTLP/JSON requires for 64-bit integers
+func (i Int64String) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(i), 10))
+}
+
+// Uint64String supports unmarshaling from both strings and numbers, as OTLP/JSON encodes
+// 64-bit counts as strings while many hand-written captures use plain numbers.
+type Uint64String uint64
+
+func (u *Uint64String) UnmarshalJSON(data []byte) error {
+	// If quoted string
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		val, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		*u = Uint64String(val)
+		return nil
+	}
+
+	// Else: assume it's a number
+	var val uint64
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+	*u = Uint64String(val)
+	return nil
+}
+
+// MarshalJSON writes the value as a quoted string, as OTLP/JSON requires for 64-bit integers
+func (u Uint64String) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(u), 10))
+}
@@ -0,0 +1,183 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"sync"
+)
+
+// Supported value generator types
+const (
+	GeneratorRandomWalk = "random_walk" // Drifts from the captured value by up to Step (relative) per iteration
+	GeneratorJitter     = "jitter"      // Gaussian noise with relative StdDev around the captured value
+	GeneratorRange      = "range"       // Uniformly distributed between Min and Max, both shifted by ReplicaOffset
+	GeneratorOffset     = "offset"      // Captured value shifted by ReplicaOffset only
+)
+
+// ValueGenerator is one entry of the value_generators list in config.yaml. It applies to
+// every gauge and non-monotonic sum whose metric name matches the Match glob.
+type ValueGenerator struct {
+	Match         string   `yaml:"match"`
+	Type          string   `yaml:"type"`
+	Step          float64  `yaml:"step"`
+	StdDev        float64  `yaml:"stddev"`
+	Min           *float64 `yaml:"min"`
+	Max           *float64 `yaml:"max"`
+	ReplicaOffset float64  `yaml:"replica_offset"` // Relative offset per replica index, e.g. 0.05 = +5% per replica
+}
+
+// Validate checks the pattern and the parameters the generator type needs
+func (g ValueGenerator) Validate() error {
+	if _, err := path.Match(g.Match, ""); err != nil || g.Match == "" {
+		return fmt.Errorf("invalid match pattern %q", g.Match)
+	}
+	if g.Min != nil && g.Max != nil && *g.Min > *g.Max {
+		return fmt.Errorf("%s: min %g is greater than max %g", g.Match, *g.Min, *g.Max)
+	}
+	switch g.Type {
+	case GeneratorRandomWalk:
+		if g.Step <= 0 {
+			return fmt.Errorf("%s: random_walk needs step > 0", g.Match)
+		}
+	case GeneratorJitter:
+		if g.StdDev <= 0 {
+			return fmt.Errorf("%s: jitter needs stddev > 0", g.Match)
+		}
+	case GeneratorRange:
+		if g.Min == nil || g.Max == nil {
+			return fmt.Errorf("%s: range needs both min and max", g.Match)
+		}
+	case GeneratorOffset:
+	default:
+		return fmt.Errorf("%s: unknown generator type %q (must be %s, %s, %s or %s)", g.Match, g.Type, GeneratorRandomWalk, GeneratorJitter, GeneratorRange, GeneratorOffset)
+	}
+	return nil
+}
+
+// seriesKey identifies a data point of one replica by its position in the capture, which
+// is stable because every iteration starts from the same file
+type seriesKey struct {
+	replica, resource, scope, metric, point int
+}
+
+// ValueMutator applies the configured generators to copies of the capture. It keeps the
+// random walk state per series, so it must be shared across iterations.
+type ValueMutator struct {
+	generators []ValueGenerator
+	mu         sync.Mutex
+	walks      map[seriesKey]float64
+}
+
+func NewValueMutator(generators []ValueGenerator) *ValueMutator {
+	return &ValueMutator{generators: generators, walks: make(map[seriesKey]float64)}
+}
+
+// generatorFor returns the first generator whose pattern matches the metric name
+func (m *ValueMutator) generatorFor(name string) *ValueGenerator {
+	for i := range m.generators {
+		if ok, _ := path.Match(m.generators[i].Match, name); ok {
+			return &m.generators[i]
+		}
+	}
+	return nil
+}
+
+// Apply rewrites the values of one replica's copy of the capture in place
+func (m *ValueMutator) Apply(metricsFile *MetricsFile, replica int) {
+	if m == nil || len(m.generators) == 0 {
+		return
+	}
+	for r := range metricsFile.ResourceMetrics {
+		for s := range metricsFile.ResourceMetrics[r].ScopeMetrics {
+			metrics := metricsFile.ResourceMetrics[r].ScopeMetrics[s].Metrics
+			for i := range metrics {
+				gen := m.generatorFor(metrics[i].Name)
+				if gen == nil {
+					continue
+				}
+				var points []DataPoint
+				switch {
+				case metrics[i].Gauge != nil:
+					points = metrics[i].Gauge.DataPoints
+				case metrics[i].Sum != nil && !metrics[i].Sum.IsMonotonic:
+					// Monotonic sums are counters and must never go down
+					points = metrics[i].Sum.DataPoints
+				}
+				for p := range points {
+					m.mutate(gen, &points[p], seriesKey{replica, r, s, i, p}, replica)
+				}
+			}
+		}
+	}
+}
+
+func (m *ValueMutator) mutate(gen *ValueGenerator, dp *DataPoint, key seriesKey, replica int) {
+	captured, isInt, ok := dataPointValue(dp)
+	if !ok {
+		return
+	}
+	base := captured * (1 + gen.ReplicaOffset*float64(replica))
+	rng := ReplicaRand(replica)
+
+	var value float64
+	switch gen.Type {
+	case GeneratorRandomWalk:
+		m.mu.Lock()
+		current, seen := m.walks[key]
+		if !seen {
+			current = base
+		}
+		// Steps are relative to the captured value; a zero capture walks in absolute steps
+		scale := math.Abs(base)
+		if scale == 0 {
+			scale = 1
+		}
+		current = clamp(current+gen.Step*scale*(2*rng.Float64()-1), gen.Min, gen.Max)
+		m.walks[key] = current
+		m.mu.Unlock()
+		value = current
+	case GeneratorJitter:
+		value = base * (1 + gen.StdDev*rng.NormFloat64())
+	case GeneratorRange:
+		// The offset moves the whole range, so every replica keeps a uniform distribution
+		// instead of piling up at max; min and max are not clamped to again
+		shift := 1 + gen.ReplicaOffset*float64(replica)
+		low, high := *gen.Min*shift, *gen.Max*shift
+		setDataPointValue(dp, low+rng.Float64()*(high-low), isInt)
+		return
+	default:
+		value = base
+	}
+	setDataPointValue(dp, clamp(value, gen.Min, gen.Max), isInt)
+}
+
+// dataPointValue returns the numeric value of a data point and whether it is an integer
+func dataPointValue(dp *DataPoint) (float64, bool, bool) {
+	if dp.AsDouble != nil {
+		return *dp.AsDouble, false, true
+	}
+	if val, err := strconv.ParseInt(dp.AsInt, 10, 64); err == nil {
+		return float64(val), true, true
+	}
+	return 0, false, false
+}
+
+func setDataPointValue(dp *DataPoint, value float64, isInt bool) {
+	if isInt {
+		dp.AsInt = strconv.FormatInt(int64(math.Round(value)), 10)
+		return
+	}
+	dp.AsDouble = &value
+}
+
+func clamp(value float64, min *float64, max *float64) float64 {
+	if min != nil && value < *min {
+		value = *min
+	}
+	if max != nil && value > *max {
+		value = *max
+	}
+	return value
+}
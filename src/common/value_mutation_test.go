@@ -0,0 +1,34 @@
+package common
+
+import "testing"
+
+func gaugeFile(value float64) *MetricsFile {
+	return &MetricsFile{ResourceMetrics: []ResourceMetric{{
+		ScopeMetrics: []ScopeMetric{{
+			Metrics: []Metric{{Name: "disk.usage", Gauge: &Gauge{DataPoints: []DataPoint{{AsDouble: &value}}}}},
+		}},
+	}}}
+}
+
+func TestRangeShiftsBoundsByReplicaOffset(t *testing.T) {
+	min, max := 100.0, 200.0
+	mutator := NewValueMutator([]ValueGenerator{{Match: "disk.*", Type: GeneratorRange, Min: &min, Max: &max, ReplicaOffset: 0.5}})
+
+	for replica, bounds := range map[int][2]float64{0: {100, 200}, 2: {200, 400}} {
+		atMax := 0
+		for i := 0; i < 200; i++ {
+			file := gaugeFile(0)
+			mutator.Apply(file, replica)
+			value := *file.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Gauge.DataPoints[0].AsDouble
+			if value < bounds[0] || value > bounds[1] {
+				t.Fatalf("replica %d: %g outside [%g, %g]", replica, value, bounds[0], bounds[1])
+			}
+			if value == max {
+				atMax++
+			}
+		}
+		if atMax > 1 {
+			t.Errorf("replica %d: %d of 200 values are exactly max", replica, atMax)
+		}
+	}
+}
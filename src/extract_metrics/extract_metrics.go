@@ -12,17 +12,19 @@ import (
 func main() {
 	configPath := flag.String("config", "config.yaml", "Path to the configuration file")
 	helpFlag := flag.Bool("h", false, "Display usage information")
+	common.RegisterValidateFlag()
 	flag.Parse()
 
 	if *helpFlag {
 		fmt.Println("Usage: metrics_loadgen [options]")
 		fmt.Println("Options:")
 		fmt.Println("  --config=<path>  Specify the configuration file (default: config.yaml)")
+		fmt.Println("  -validate        Check the config file, report every problem and exit")
 		fmt.Println("  -h               Display this help message")
 		os.Exit(0)
 	}
 
-	common.LoadConfig(*configPath)
+	common.LoadConfig(*configPath, "extract_metrics")
 	ProcessMetricsFile()
 	log.Println("🏁 Processing complete.")
 }
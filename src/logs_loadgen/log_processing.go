@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hagen-p/o11y-go-loadgen/src/common"
+)
+
+// sink receives the replayed logs; built from the sinks section of config.yaml
+var sink common.Sink
+
+// pool runs the batches of every replica; created in main once the config is loaded
+var pool *common.ReplicaPool
+
+// recordPacer spreads sends so that on average no more than rate records go out per second
+type recordPacer struct {
+	rate float64
+	next time.Time
+}
+
+func newRecordPacer(rate int) *recordPacer {
+	return &recordPacer{rate: float64(rate), next: time.Now()}
+}
+
+// Wait blocks until a batch of n records may be sent
+func (p *recordPacer) Wait(n int) {
+	now := time.Now()
+	if p.next.Before(now) {
+		// Don't let idle time accumulate into a burst
+		p.next = now
+	}
+	time.Sleep(time.Until(p.next))
+	p.next = p.next.Add(time.Duration(float64(n) / p.rate * float64(time.Second)))
+}
+
+// processLogFile replays the log capture once for every replica, in paced batches
+func processLogFile(filePath string, replicas int, pacer *recordPacer) {
+	expandedPath, err := common.ExpandPath(filePath)
+	if err != nil {
+		log.Printf("❌ Failed to expand file path: %v", err)
+		time.Sleep(time.Second)
+		return
+	}
+
+	replacementsFile := filepath.Join(filepath.Dir(expandedPath), "replacements.json")
+	replacements := common.LoadReplacements(replacementsFile)
+
+	log.Printf("📖 Processing log file: %s", expandedPath)
+
+	data, err := os.ReadFile(expandedPath)
+	if err != nil {
+		log.Printf("❌ Failed to read file: %s, error: %v", expandedPath, err)
+		time.Sleep(time.Second)
+		return
+	}
+
+	var logFile common.LogFile
+	if err := json.Unmarshal(data, &logFile); err != nil {
+		log.Printf("❌ Failed to unmarshal JSON: %v", err)
+		time.Sleep(time.Second)
+		return
+	}
+
+	var pass sync.WaitGroup
+	for clusterIndex := 0; clusterIndex < replicas; clusterIndex++ {
+		logsCopy := common.DeepCopyLogFile(logFile)
+		rewriter := common.NewIdentityRewriter(clusterIndex, replacements)
+		for i := range logsCopy.ResourceLogs {
+			rewriter.Rewrite(&logsCopy.ResourceLogs[i].Resource)
+		}
+
+		for _, batch := range splitLogFile(logsCopy, common.LogsBatchSize) {
+			pacer.Wait(countLogRecords(batch))
+			pass.Add(1)
+			pool.Submit(clusterIndex, func() {
+				defer pass.Done()
+				updateLogTimestamps(&batch, time.Now().UnixNano())
+				if err := sink.Export(context.Background(), common.ToOTLPLogRequest(batch)); err != nil {
+					log.Printf("⚠️ %v", err)
+				}
+			})
+		}
+	}
+	pass.Wait()
+
+	replacements.Save(replacementsFile)
+}
+
+// splitLogFile cuts a capture into batches of at most size records, keeping every record
+// under its original resource and scope
+func splitLogFile(logFile common.LogFile, size int) []common.LogFile {
+	var (
+		batches []common.LogFile
+		current common.LogFile
+		count   int
+	)
+	flush := func() {
+		if count > 0 {
+			batches = append(batches, current)
+		}
+		current, count = common.LogFile{}, 0
+	}
+
+	for _, rl := range logFile.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			for start := 0; start < len(sl.Logs); {
+				end := min(start+size-count, len(sl.Logs))
+				current.ResourceLogs = append(current.ResourceLogs, common.ResourceLog{
+					Resource:  rl.Resource,
+					SchemaUrl: rl.SchemaUrl,
+					ScopeLogs: []common.ScopeLog{{Scope: sl.Scope, SchemaUrl: sl.SchemaUrl, Logs: sl.Logs[start:end]}},
+				})
+				count += end - start
+				start = end
+				if count >= size {
+					flush()
+				}
+			}
+		}
+	}
+	flush()
+	return batches
+}
+
+func countLogRecords(logFile common.LogFile) int {
+	count := 0
+	for _, rl := range logFile.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			count += len(sl.Logs)
+		}
+	}
+	return count
+}
+
+// updateLogTimestamps moves every record to now, keeping the original gap between the
+// event time and the time the record was observed
+func updateLogTimestamps(logFile *common.LogFile, now int64) {
+	for i := range logFile.ResourceLogs {
+		for j := range logFile.ResourceLogs[i].ScopeLogs {
+			records := logFile.ResourceLogs[i].ScopeLogs[j].Logs
+			for k := range records {
+				record := &records[k]
+				observed := now
+				recorded, err1 := strconv.ParseInt(record.TimeUnixNano, 10, 64)
+				observedOrig, err2 := strconv.ParseInt(record.ObservedTimeUnixNano, 10, 64)
+				if err1 == nil && err2 == nil && observedOrig >= recorded {
+					observed = now + (observedOrig - recorded)
+				}
+				record.TimeUnixNano = strconv.FormatInt(now, 10)
+				record.ObservedTimeUnixNano = strconv.FormatInt(observed, 10)
+			}
+		}
+	}
+}
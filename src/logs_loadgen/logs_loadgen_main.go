@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hagen-p/o11y-go-loadgen/src/common"
+)
+
+func main() {
+
+	configPath := flag.String("config", "config.yaml", "Path to the configuration file")
+	helpFlag := flag.Bool("h", false, "Display usage information")
+
+	flag.BoolVar(&common.DebugEnabled, "d", false, "Enable debug output")
+	flag.BoolVar(&common.InfoEnabled, "I", false, "Enable info-level logs to stdout")
+	common.RegisterValidateFlag()
+
+	flag.Parse()
+
+	if *helpFlag {
+		fmt.Println("Usage: logs_loadgen [options]")
+		fmt.Println("Options:")
+		fmt.Println("  --config=<path>  Specify the configuration file (default: config.yaml)")
+		fmt.Println("  -d               Enable debug logs")
+		fmt.Println("  -I               Enable info logs to stdout")
+		fmt.Println("  -validate        Check the config file, report every problem and exit")
+		fmt.Println("  -h               Display this help message")
+		os.Exit(0)
+	}
+	common.InitLogging()
+	common.LoadConfig(*configPath, "logs_loadgen")
+
+	if common.LogsFile == "" {
+		log.Fatalf("❌ No logs_file specified in config.")
+	}
+
+	var err error
+	sink, err = common.NewSink()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to create sink: %v\n", err)
+		os.Exit(1)
+	}
+	defer sink.Close()
+	common.StartReport()
+	common.StartTelemetry()
+
+	pool = common.NewReplicaPool(common.Workers, common.QueueSize)
+	log.Printf("👷 Started %d workers (queue size %d)", common.Workers, common.QueueSize)
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signalChan
+		log.Println("🛑 Stopping log replay...")
+		sink.Close()
+		common.FinishReport()
+		os.Exit(0)
+	}()
+
+	log.Printf("📜 Replaying %s at %d records/s across %d replicas", common.LogsFile, common.LogsPerSecond, common.NoReplicas)
+	pacer := newRecordPacer(common.LogsPerSecond)
+	for {
+		processLogFile(common.LogsFile, common.NoReplicas, pacer)
+		if common.DebugEnabled {
+			pool.Close()
+			common.FinishReport()
+			os.Exit(0)
+		}
+	}
+}
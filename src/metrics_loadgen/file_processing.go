@@ -2,25 +2,26 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/hagen-p/o11y-go-loadgen/src/common"
 )
 
 // Process single JSON file
-func processSingleFile() {
+func processSingleFile(replicas int, interval time.Duration) {
 	if common.InputFile == "" {
 		log.Println("❌ No input file specified in config.")
 		return
 	}
-	processJSONFile(common.InputFile)
+	processJSONFile(common.InputFile, replicas, interval)
 }
 
 // Process JSON files in the input directory
-func processFiles() {
+func processFiles(replicas int, interval time.Duration) {
 	expandedPath, err := common.ExpandPath(common.InputDir)
 	if err != nil {
 		log.Printf("❌ Failed to expand input directory path: %v", err)
@@ -36,13 +37,24 @@ func processFiles() {
 	for _, file := range files {
 		if filepath.Ext(file.Name()) == ".json" {
 			filePath := filepath.Join(expandedPath, file.Name())
-			processJSONFile(filePath)
+			processJSONFile(filePath, replicas, interval)
 		}
 	}
 }
 
-// Process a single JSON file
-func processJSONFile(filePath string) {
+// resetReplacements forgets the identities generated for the input file, so changed rewrite
+// rules apply to every attribute instead of only to values not seen before
+func resetReplacements() {
+	replacementsFile := filepath.Join(filepath.Dir(common.InputFile), "replacements.json")
+	if err := common.ResetReplacements(replacementsFile); err != nil {
+		log.Printf("⚠️ Failed to reset %s: %v", replacementsFile, err)
+		return
+	}
+	log.Printf("🔄 Rewrite rules changed, generating new identities")
+}
+
+// Process a single JSON file, sending it once per replica
+func processJSONFile(filePath string, replicas int, interval time.Duration) {
 	expandedPath, err := common.ExpandPath(filePath)
 	if err != nil {
 		log.Printf("❌ Failed to expand file path: %v", err)
@@ -51,12 +63,7 @@ func processJSONFile(filePath string) {
 
 	dir := filepath.Dir(expandedPath)
 	replacementsFile := filepath.Join(dir, "replacements.json")
-	replacements := make(map[string]string)
-
-	// Load previous replacements if the file exists
-	if data, err := os.ReadFile(replacementsFile); err == nil {
-		_ = json.Unmarshal(data, &replacements)
-	}
+	replacements := common.LoadReplacements(replacementsFile)
 
 	log.Printf("📖 Processing file: %s", expandedPath)
 
@@ -72,73 +79,38 @@ func processJSONFile(filePath string) {
 		return
 	}
 
-	nodeNameCounter := make(map[string]int)
-
-	for clusterIndex := 0; clusterIndex < common.NoReplicas; clusterIndex++ {
-		metricsCopy := common.DeepCopyMetricsFile(metricsFile)
-		clusterName := fmt.Sprintf("%s-%02d", common.BaseClusterName, clusterIndex)
-		var resolvedNodeName string
-
-		for resIdx := range metricsCopy.ResourceMetrics {
-			resource := &metricsCopy.ResourceMetrics[resIdx].Resource
-			for i, attr := range resource.Attributes {
-				key := attr.Key
-				val := attr.Value.StringValue
-
-				switch key {
-				case "k8s.cluster.name":
-					mappedKey := fmt.Sprintf("cluster:%s:%02d", val, clusterIndex)
-					if replacement, ok := replacements[mappedKey]; ok {
-						resource.Attributes[i].Value.StringValue = replacement
-					} else {
-						replacements[mappedKey] = clusterName
-						resource.Attributes[i].Value.StringValue = clusterName
-						log.Printf("🔄 Updating cluster name: %s -> %s", val, clusterName)
-					}
-
-				case "k8s.node.name":
-					counterKey := fmt.Sprintf("%s-%02d", val, clusterIndex)
-					count := nodeNameCounter[counterKey]
-					nodeNameCounter[counterKey]++
-					letter1 := 'A' + (count / 26)
-					letter2 := 'A' + (count % 26)
-					suffix := fmt.Sprintf("%c%c", letter1, letter2)
-					newNodeName := fmt.Sprintf("%s-%s-%02d", val, suffix, clusterIndex)
-					mappedKey := fmt.Sprintf("node:%s:%02d:%d", val, clusterIndex, count)
-					if replacement, ok := replacements[mappedKey]; ok {
-						resource.Attributes[i].Value.StringValue = replacement
-					} else {
-						replacements[mappedKey] = newNodeName
-						resource.Attributes[i].Value.StringValue = newNodeName
-						log.Printf("🔄 Updating node name: %s -> %s", val, newNodeName)
-					}
-					resolvedNodeName = resource.Attributes[i].Value.StringValue
-
-				case "host.name":
-					if resolvedNodeName != "" {
-						resource.Attributes[i].Value.StringValue = resolvedNodeName
-						log.Printf("🔄 Syncing host name to node name: %s", resolvedNodeName)
-					}
-
-				case "k8s.pod.uid":
-					mappedKey := fmt.Sprintf("pod:%s:%02d", val, clusterIndex)
-					if replacement, ok := replacements[mappedKey]; ok {
-						resource.Attributes[i].Value.StringValue = replacement
-					} else {
-						newUID := fmt.Sprintf("uid-%s-%02d", val[:8], clusterIndex)
-						replacements[mappedKey] = newUID
-						resource.Attributes[i].Value.StringValue = newUID
-						log.Printf("🔄 Updating pod UID: %s -> %s", val, newUID)
-					}
-				}
-			}
-		}
-
-		updateTimestamps(&metricsCopy)
-		outputProcessedJSON(metricsCopy)
+	// Replicas are generated and sent concurrently; wait for all of them before
+	// persisting the replacements so the next iteration starts from a complete map
+	var iteration sync.WaitGroup
+	for clusterIndex := 0; clusterIndex < replicas; clusterIndex++ {
+		iteration.Add(1)
+		pool.Submit(clusterIndex, func() {
+			defer iteration.Done()
+			processReplica(metricsFile, clusterIndex, replacements, interval)
+		})
 	}
+	iteration.Wait()
+
+	replacements.Save(replacementsFile)
+}
 
-	if jsonData, err := json.MarshalIndent(replacements, "", "  "); err == nil {
-		_ = os.WriteFile(replacementsFile, jsonData, 0644)
+// processReplica rewrites the identity attributes of one simulated cluster and sends it
+func processReplica(metricsFile common.MetricsFile, clusterIndex int, replacements *common.ReplacementMap, interval time.Duration) {
+	metricsCopy := common.DeepCopyMetricsFile(metricsFile)
+	common.ExpandCardinality(&metricsCopy, common.ResourceCardinality)
+	mutator.Apply(&metricsCopy, clusterIndex)
+
+	rewriter := common.NewIdentityRewriter(clusterIndex, replacements)
+	if common.ResourceCardinality.Enabled() {
+		rewriter.WithExpandedResources()
+	}
+	for resIdx := range metricsCopy.ResourceMetrics {
+		rewriter.RewriteResourceMetric(&metricsCopy.ResourceMetrics[resIdx])
 	}
+
+	common.UpdateTimestamps(&metricsCopy)
+	now := time.Now().UnixNano()
+	counters.Apply(&metricsCopy, clusterIndex, now, interval)
+	histograms.Apply(&metricsCopy, clusterIndex, now, interval)
+	outputProcessedJSON(metricsCopy)
 }
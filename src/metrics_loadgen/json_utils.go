@@ -1,12 +1,10 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"time"
 
 	collector "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
@@ -16,6 +14,24 @@ import (
 	"github.com/hagen-p/o11y-go-loadgen/src/common"
 )
 
+// sink receives the processed metrics; built from the sinks section of config.yaml
+var sink common.Sink
+
+// mutator varies the captured values; it keeps random walk state across iterations
+var mutator *common.ValueMutator
+
+// counters keeps cumulative sums increasing across iterations
+var counters *common.CounterTracker
+
+// histograms samples new observations into histograms, accumulating cumulative ones
+var histograms *common.HistogramSynthesizer
+
+// watcher reloads config.yaml between iterations when it changes or on SIGHUP
+var watcher *common.ConfigWatcher
+
+// pool is shared by every processed file; created in main once the config is loaded
+var pool *common.ReplicaPool
+
 func updateClusterNames(metricsFile *common.MetricsFile) {
 	for clusterIndex := 0; clusterIndex < common.NoReplicas; clusterIndex++ {
 		clusterName := fmt.Sprintf("%s-%d", common.BaseClusterName, clusterIndex)
@@ -30,52 +46,6 @@ func updateClusterNames(metricsFile *common.MetricsFile) {
 	}
 }
 
-func updateTimestamps(metricsFile *common.MetricsFile) {
-	currentTime := time.Now().UnixNano()
-
-	for _, rm := range metricsFile.ResourceMetrics {
-		for _, sm := range rm.ScopeMetrics {
-			for _, metric := range sm.Metrics {
-				if metric.Gauge != nil {
-					for i := range metric.Gauge.DataPoints {
-						startStr := metric.Gauge.DataPoints[i].StartTimeUnixNano
-						endStr := metric.Gauge.DataPoints[i].TimeUnixNano
-
-						const defaultDiff = 5
-						var (
-							originalStartTime time.Duration
-							originalTime      time.Duration
-							err1, err2        error
-						)
-
-						if startStr != "" {
-							originalStartTime, err1 = time.ParseDuration(startStr + "ns")
-						} else {
-							err1 = fmt.Errorf("StartTimeUnixNano is empty")
-						}
-
-						if endStr != "" {
-							originalTime, err2 = time.ParseDuration(endStr + "ns")
-						} else {
-							err2 = fmt.Errorf("TimeUnixNano is empty")
-						}
-
-						timeDiff := time.Duration(defaultDiff)
-						if err1 == nil && err2 == nil {
-							timeDiff = originalTime - originalStartTime
-						} else {
-							log.Printf("⚠️ Using default 5ns diff due to timestamp parse error: %v, %v", err1, err2)
-						}
-
-						metric.Gauge.DataPoints[i].StartTimeUnixNano = fmt.Sprintf("%d", currentTime)
-						metric.Gauge.DataPoints[i].TimeUnixNano = fmt.Sprintf("%d", currentTime+int64(timeDiff))
-					}
-				}
-			}
-		}
-	}
-}
-
 func outputProcessedJSON(metricsFile common.MetricsFile) {
 	var otlpResourceMetrics []*metricpb.ResourceMetrics
 
@@ -102,57 +72,15 @@ func outputProcessedJSON(metricsFile common.MetricsFile) {
 		ResourceMetrics: otlpResourceMetrics,
 	}
 
-	outputJSON, err := protojson.Marshal(otlpRequest)
-	if err != nil {
-		log.Printf("❌ Failed to marshal OTLP JSON: %v", err)
-		return
-	}
-
-	otlpURL := common.CollectorURL + "/v1/metrics"
-
-	req, err := http.NewRequest("POST", otlpURL, bytes.NewBuffer(outputJSON))
-	if err != nil {
-		log.Printf("❌ Failed to create HTTP request: %v", err)
-		return
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
 	if common.DebugEnabled {
-		_ = os.WriteFile("console.out", outputJSON, 0644)
-	}
-	/*if common.DebugEnabled { // DEBUG output
-		fmt.Println("DEBUG: Raw marshaled JSON:")
-		fmt.Println(string(outputJSON))
-		req, err = common.DumpAndPauseRequest(req, outputJSON)
-		if err != nil {
-			log.Fatal(err)
+		if outputJSON, err := protojson.Marshal(otlpRequest); err == nil {
+			_ = os.WriteFile("console.out", outputJSON, 0644)
+		} else {
+			log.Printf("❌ Failed to marshal OTLP JSON: %v", err)
 		}
 	}
-	*/
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("❌ Failed to send OTLP data: %v", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	/*
-		if common.DebugEnabled { // Debug output
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				log.Printf("⚠️ Could not read response body: %v", err)
-			} else if len(body) > 0 {
-				log.Println("📩 Collector response body:")
-				log.Println(string(body))
-			}
-		}
-	*/
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		log.Printf("✅ Successfully sent OTLP metrics to %s (status: %s)", otlpURL, resp.Status)
-	} else {
-		log.Printf("⚠️ Unexpected response from OTLP receiver: %s", resp.Status)
+
+	if err := sink.Export(context.Background(), otlpRequest); err != nil {
+		log.Printf("⚠️ %v", err)
 	}
 }
@@ -15,10 +15,13 @@ import (
 func main() {
 
 	configPath := flag.String("config", "config.yaml", "Path to the configuration file")
+	scenarioPath := flag.String("scenario", "", "Path to a scenario file whose phases are run in order")
 	helpFlag := flag.Bool("h", false, "Display usage information")
 
 	flag.BoolVar(&common.DebugEnabled, "d", false, "Enable debug output")
 	flag.BoolVar(&common.InfoEnabled, "I", false, "Enable info-level logs to stdout")
+	common.RegisterSeedFlag()
+	common.RegisterValidateFlag()
 
 	flag.Parse()
 
@@ -26,13 +29,43 @@ func main() {
 		fmt.Println("Usage: metrics_loadgen [options]")
 		fmt.Println("Options:")
 		fmt.Println("  --config=<path>  Specify the configuration file (default: config.yaml)")
+		fmt.Println("  -scenario=<path> Run the phases of a scenario file, then exit")
 		fmt.Println("  -d               Enable debug logs")
 		fmt.Println("  -I               Enable info logs to stdout")
+		fmt.Println("  -seed=<n>        Seed for generated values, for reproducible runs")
+		fmt.Println("  -validate        Check the config file, report every problem and exit")
 		fmt.Println("  -h               Display this help message")
 		os.Exit(0)
 	}
 	common.InitLogging()
-	common.LoadConfig(*configPath)
+	var scenario common.Scenario
+	if *scenarioPath != "" {
+		scenario = common.LoadScenario(*scenarioPath)
+	}
+	common.LoadConfig(*configPath, "metrics_loadgen")
+	common.InitRandom()
+
+	var err error
+	sink, err = common.NewSink()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to create sink: %v\n", err)
+		os.Exit(1)
+	}
+	defer sink.Close()
+	common.StartReport()
+	common.StartTelemetry()
+
+	mutator = common.NewValueMutator(common.ValueGenerators)
+	counters = common.NewCounterTracker(common.Counters, common.Interval)
+	histograms = common.NewHistogramSynthesizer(common.Histograms, common.Interval)
+
+	pool = common.NewReplicaPool(common.Workers, common.QueueSize)
+	log.Printf("👷 Started %d workers (queue size %d)", common.Workers, common.QueueSize)
+	if *scenarioPath == "" {
+		watcher = common.WatchConfig(*configPath, "metrics_loadgen")
+	} else {
+		log.Printf("🎬 Running scenario %s, config reload is off", *scenarioPath)
+	}
 
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
@@ -40,15 +73,117 @@ func main() {
 	go func() {
 		<-signalChan
 		log.Println("🛑 Stopping JSON processing...")
+		sink.Close()
+		common.FinishReportPhase()
+		common.FinishReport()
 		os.Exit(0)
 	}()
 
+	if *scenarioPath != "" {
+		runScenario(scenario)
+		pool.Close()
+		log.Println("🏁 Scenario complete.")
+	} else {
+		runLoadProfile()
+		pool.Close()
+		log.Println("🏁 Load profile complete.")
+	}
+	common.FinishReport()
+}
+
+// runScenario runs the phases of a scenario in order, each for its duration, and reports
+// every phase on its own. The load profile of config.yaml is not used.
+func runScenario(scenario common.Scenario) {
+	log.Printf("🎬 Scenario with %d phases (%s)", len(scenario.Phases), scenario.Total())
+	for i, phase := range scenario.Phases {
+		applyChanges(common.ApplyPhase(phase))
+		log.Printf("🎬 Phase %d/%d %s: %d replicas every %s for %s", i+1, len(scenario.Phases), phase.Name, common.NoReplicas, common.Interval, phase.Duration)
+		common.StartReportPhase(phase.Name)
+
+		phaseEnd := time.Now().Add(phase.Duration)
+		for time.Now().Before(phaseEnd) {
+			iterationStart := time.Now()
+			processSingleFile(common.NoReplicas, common.Interval)
+			next := iterationStart.Add(common.Interval)
+			if next.After(phaseEnd) {
+				next = phaseEnd
+			}
+			time.Sleep(time.Until(next))
+		}
+
+		common.FinishReportPhase()
+		if phase.Pause > 0 {
+			log.Printf("⏸️ Pausing for %s", phase.Pause)
+			time.Sleep(phase.Pause)
+		}
+	}
+}
+
+// runLoadProfile sends the input once per interval, following the configured load profile.
+// Without a profile it sends NoReplicas replicas every Interval forever.
+func runLoadProfile() {
+	if len(common.Profile) > 0 {
+		log.Printf("📈 Running load profile with %d stages (%s)", len(common.Profile), common.Profile.Total())
+	}
+
+	start := time.Now()
+	lastStage, lastReplicas, lastInterval := -1, 0, time.Duration(0)
+
 	for {
-		//processFiles()
-		processSingleFile()
-		time.Sleep(10 * time.Second)
+		iterationStart := time.Now()
+		applyReload()
+		replicas, interval := common.NoReplicas, common.Interval
+		if len(common.Profile) > 0 {
+			var (
+				stage int
+				done  bool
+			)
+			replicas, interval, stage, done = common.Profile.At(time.Since(start), common.Interval)
+			if done {
+				return
+			}
+			if stage != lastStage {
+				log.Printf("📈 Entering stage %d/%d: %s", stage+1, len(common.Profile), common.Profile[stage].Type)
+				lastStage = stage
+			}
+		}
+		if replicas != lastReplicas || interval != lastInterval {
+			log.Printf("📈 Sending %d replicas every %s", replicas, interval)
+			lastReplicas, lastInterval = replicas, interval
+		}
+
+		//processFiles(replicas, interval)
+		processSingleFile(replicas, interval)
 		if common.DebugEnabled {
+			pool.Close()
+			common.FinishReport()
 			os.Exit(0)
 		}
+
+		// Intervals are measured start-to-start, so the send rate stays at replicas per
+		// interval as long as an iteration finishes within the interval
+		time.Sleep(time.Until(iterationStart.Add(interval)))
+	}
+}
+
+// applyReload applies a pending config reload. It runs between two iterations, when every
+// replica of the previous one has been sent.
+func applyReload() {
+	applyChanges(watcher.Reload())
+}
+
+// applyChanges recreates what depends on the changed config keys
+func applyChanges(changes common.ConfigChanges) {
+	if changes.Has(common.SinkKeys...) {
+		newSink, err := common.NewSink()
+		if err != nil {
+			log.Printf("❌ Keeping the current sink, failed to create the new one: %v", err)
+		} else {
+			sink.Close()
+			sink = newSink
+		}
+	}
+	if changes.Has("rewrite_rules") {
+		resetReplacements()
 	}
 }
@@ -1,38 +1,51 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
 	"time"
 
 	"github.com/hagen-p/o11y-go-loadgen/src/common"
 )
 
-type Config struct {
-	CollectorURL string `json:"collectorURL"`
-	InputFile    string `json:"input_file"`
-}
-
 type MetricsFile = common.MetricsFile
 
 func main() {
-	cfg := Config{
-		CollectorURL: "http://localhost:5318",
-		InputFile:    "agent-new.json",
+	configPath := flag.String("config", "config.yaml", "Path to the configuration file")
+	flag.BoolVar(&common.DebugEnabled, "d", false, "Enable debug output")
+	flag.BoolVar(&common.InfoEnabled, "I", false, "Enable info-level logs to stdout")
+	// node_loadgen sends the input file as it is, so there is nothing to seed. The flag is
+	// accepted so both loadgens can be started with the same arguments.
+	flag.Int64Var(&common.Seed, "seed", 0, "No effect; accepted for parity with metrics_loadgen")
+	common.RegisterValidateFlag()
+	flag.Parse()
+
+	common.InitLogging()
+	common.LoadConfig(*configPath, "node_loadgen")
+	common.StartTelemetry()
+
+	log.Printf("INFO: Collector URL loaded from config: %s", common.CollectorURL)
+	log.Printf("INFO: Input file expanded to: %s", common.InputFile)
+	log.Printf("INFO: Sending as %s with compression %s", common.Protocol, common.Compression)
+	log.Printf("INFO: Sending every %s", common.Interval)
+
+	sink, err := common.NewSink()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to create sink: %v\n", err)
+		os.Exit(1)
 	}
+	defer sink.Close()
 
-	log.Printf("INFO: Collector URL loaded from config: %s", cfg.CollectorURL)
-	log.Printf("INFO: Input file expanded to: %s", cfg.InputFile)
-
-	ticker := time.NewTicker(10 * time.Second)
+	ticker := time.NewTicker(common.Interval)
 	defer ticker.Stop()
 
 	for {
-		metricsFile, err := LoadAndDecodeMetricsFile(cfg.InputFile)
+		metricsFile, err := LoadAndDecodeMetricsFile(common.InputFile)
 		if err != nil {
 			log.Fatalf("❌ Failed to decode metrics file: %v", err)
 		}
@@ -41,9 +54,8 @@ func main() {
 
 		common.UpdateTimestamps(metricsFile)
 
-		err = SendPayload(cfg.CollectorURL, metricsFile)
-		if err != nil {
-			log.Printf("❌ Failed to send payload: %v", err)
+		if err := sink.Export(context.Background(), common.ToOTLPRequest(*metricsFile)); err != nil {
+			log.Printf("⚠️ %v", err)
 		} else {
 			log.Printf("✅ Payload sent successfully")
 		}
@@ -71,57 +83,3 @@ func LoadAndDecodeMetricsFile(path string) (*MetricsFile, error) {
 
 	return &metricsFile, nil
 }
-
-func SendPayload(url string, metricsFile *MetricsFile) error {
-	body, err := json.Marshal(metricsFile)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, ioutil.NopCloser((os.Stdin)))
-	req.Body = ioutil.NopCloser((os.Stdin)) // overwrite for reuse
-	req, err = http.NewRequest("POST", url, ioutil.NopCloser((os.Stdin)))
-	req.Body = ioutil.NopCloser((os.Stdin))
-	req, err = http.NewRequest("POST", url, ioutil.NopCloser((os.Stdin)))
-	req.Body = ioutil.NopCloser((os.Stdin))
-
-	req, err = http.NewRequest("POST", url, ioutil.NopCloser((os.Stdin)))
-	req, err = http.NewRequest("POST", url, ioutil.NopCloser((os.Stdin)))
-	req, err = http.NewRequest("POST", url, ioutil.NopCloser((os.Stdin)))
-	req.Body = ioutil.NopCloser((os.Stdin))
-	req.Body = ioutil.NopCloser((os.Stdin))
-	req.Body = ioutil.NopCloser((os.Stdin))
-
-	req, err = http.NewRequest("POST", url, ioutil.NopCloser((os.Stdin)))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Body = ioutil.NopCloser((os.Stdin))
-	req.Body = ioutil.NopCloser((os.Stdin))
-	req.Body = ioutil.NopCloser((os.Stdin))
-	req.Body = ioutil.NopCloser((os.Stdin))
-
-	req.Body = ioutil.NopCloser((os.Stdin))
-	req.Body = ioutil.NopCloser((os.Stdin))
-	req.Body = ioutil.NopCloser((os.Stdin))
-
-	req, err = http.NewRequest("POST", url, ioutil.NopCloser((os.Stdin)))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Body = ioutil.NopCloser((os.Stdin))
-	req.Body = ioutil.NopCloser((os.Stdin))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status: %s", resp.Status)
-	}
-
-	return nil
-}
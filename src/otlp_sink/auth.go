@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// serverTLS loads the server certificate and, for mTLS, the CA client certificates must
+// be signed by. It returns nil when no certificate is configured.
+func serverTLS(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		if clientCAFile != "" {
+			return nil, fmt.Errorf("-tls-client-ca needs -tls-cert and -tls-key")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", clientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// requiredHeader is a header every request must carry, e.g. X-SF-Token=secret
+type requiredHeader struct {
+	name  string
+	value string
+}
+
+// Set parses name=value, so requiredHeader can be used as a flag
+func (h *requiredHeader) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	h.name, h.value = name, value
+	return nil
+}
+
+func (h *requiredHeader) String() string {
+	if h == nil || h.name == "" {
+		return ""
+	}
+	return h.name + "=" + h.value
+}
+
+// allowHTTP answers 401 if the request lacks the required header
+func (h requiredHeader) allowHTTP(w http.ResponseWriter, r *http.Request, signal string) bool {
+	if h.name == "" || r.Header.Get(h.name) == h.value {
+		return true
+	}
+	stats[signal].Rejected.Add(1)
+	log.Printf("❌ Rejected %s request: missing or wrong %s header", signal, h.name)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+	return false
+}
+
+// allowGRPC returns Unauthenticated if the call lacks the required metadata
+func (h requiredHeader) allowGRPC(ctx context.Context, signal string) error {
+	if h.name == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(h.name) {
+		if value == h.value {
+			return nil
+		}
+	}
+	stats[signal].Rejected.Add(1)
+	log.Printf("❌ Rejected %s request: missing or wrong %s metadata", signal, h.name)
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
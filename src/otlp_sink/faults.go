@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// faults describes the latency and errors injected into every signal
+type faults struct {
+	latency    time.Duration // Added before answering
+	jitter     time.Duration // Up to this much extra latency, uniformly distributed
+	errorRate  float64       // Fraction of requests answered with errorCode
+	errorCode  int           // HTTP status; mapped to the matching gRPC code
+	retryAfter time.Duration // Sent as Retry-After / RetryInfo with injected errors (0 = none)
+}
+
+// apply sleeps for the configured latency and reports whether this request should fail
+func (f faults) apply() bool {
+	delay := f.latency
+	if f.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(f.jitter)))
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return f.errorRate > 0 && rand.Float64() < f.errorRate
+}
+
+// writeHTTPError answers an injected failure over HTTP
+func (f faults) writeHTTPError(w http.ResponseWriter) {
+	if f.retryAfter > 0 {
+		w.Header().Set("Retry-After", formatSeconds(f.retryAfter))
+	}
+	http.Error(w, "injected failure", f.errorCode)
+}
+
+// grpcError returns the gRPC status matching the configured HTTP status code, as the
+// OTLP specification maps them
+func (f faults) grpcError() error {
+	code := codes.Unknown
+	switch f.errorCode {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusInternalServerError:
+		code = codes.Internal
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		code = codes.Unavailable
+	}
+	st := status.New(code, "injected failure")
+	if f.retryAfter > 0 {
+		if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(f.retryAfter)}); err == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
+// formatSeconds renders a Retry-After value, rounding up to whole seconds
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
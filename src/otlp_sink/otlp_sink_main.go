@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/hagen-p/o11y-go-loadgen/src/common"
+)
+
+func main() {
+
+	httpAddr := flag.String("http", "localhost:4318", "OTLP/HTTP listen address (empty to disable)")
+	grpcAddr := flag.String("grpc", "localhost:4317", "OTLP/gRPC listen address (empty to disable)")
+	reportEvery := flag.Duration("report", 10*time.Second, "Interval between printed counter reports (0 to disable)")
+	tlsCert := flag.String("tls-cert", "", "Server certificate; serves HTTPS and gRPC over TLS when set")
+	tlsKey := flag.String("tls-key", "", "Server private key")
+	tlsClientCA := flag.String("tls-client-ca", "", "Require client certificates signed by this CA (mTLS)")
+	var required requiredHeader
+	flag.Var(&required, "require-header", "Reject requests without this header, as name=value (e.g. X-SF-Token=secret)")
+	helpFlag := flag.Bool("h", false, "Display usage information")
+
+	var f faults
+	flag.DurationVar(&f.latency, "latency", 0, "Latency added to every request")
+	flag.DurationVar(&f.jitter, "jitter", 0, "Up to this much random extra latency per request")
+	flag.Float64Var(&f.errorRate, "error-rate", 0, "Fraction of requests answered with -error-code (0..1)")
+	flag.IntVar(&f.errorCode, "error-code", http.StatusServiceUnavailable, "HTTP status of injected errors, mapped to the matching gRPC code")
+	flag.DurationVar(&f.retryAfter, "retry-after", 0, "Retry-After / RetryInfo sent with injected errors (0 = none)")
+
+	flag.BoolVar(&common.DebugEnabled, "d", false, "Enable debug output")
+	flag.BoolVar(&common.InfoEnabled, "I", false, "Enable info-level logs to stdout")
+
+	flag.Parse()
+
+	if *helpFlag {
+		fmt.Println("Usage: otlp_sink [options]")
+		fmt.Println("Options:")
+		fmt.Println("  -http=<addr>        OTLP/HTTP listen address, also serves /stats (default: localhost:4318)")
+		fmt.Println("  -grpc=<addr>        OTLP/gRPC listen address (default: localhost:4317)")
+		fmt.Println("  -report=<duration>  Print counters every duration (default: 10s, 0 disables)")
+		fmt.Println("  -tls-cert=<file>    Server certificate, enables TLS on both listeners")
+		fmt.Println("  -tls-key=<file>     Server private key")
+		fmt.Println("  -tls-client-ca=<f>  Require client certificates signed by this CA (mTLS)")
+		fmt.Println("  -require-header=<name=value> Answer 401 / Unauthenticated without this header")
+		fmt.Println("  -latency=<duration> Latency added to every request")
+		fmt.Println("  -jitter=<duration>  Random extra latency per request")
+		fmt.Println("  -error-rate=<0..1>  Fraction of requests answered with an error")
+		fmt.Println("  -error-code=<code>  HTTP status of injected errors (default: 503)")
+		fmt.Println("  -retry-after=<dur>  Retry-After sent with injected errors")
+		fmt.Println("  -d                  Enable debug logs")
+		fmt.Println("  -I                  Enable info logs to stdout")
+		fmt.Println("  -h                  Display this help message")
+		os.Exit(0)
+	}
+	common.InitLogging()
+
+	if *httpAddr == "" && *grpcAddr == "" {
+		log.Fatalf("❌ Nothing to listen on: both -http and -grpc are empty")
+	}
+	if f.errorRate < 0 || f.errorRate > 1 {
+		log.Fatalf("❌ Invalid error rate: %g (must be between 0 and 1)", f.errorRate)
+	}
+	if f.errorCode < 400 || f.errorCode > 599 {
+		log.Fatalf("❌ Invalid error code: %d (must be a 4xx or 5xx status)", f.errorCode)
+	}
+
+	tlsConfig, err := serverTLS(*tlsCert, *tlsKey, *tlsClientCA)
+	if err != nil {
+		log.Fatalf("❌ Invalid TLS settings: %v", err)
+	}
+	scheme, security := "http", "plaintext"
+	if tlsConfig != nil {
+		scheme, security = "https", "TLS"
+	}
+
+	if *httpAddr != "" {
+		listener, err := net.Listen("tcp", *httpAddr)
+		if err != nil {
+			log.Fatalf("❌ Failed to listen on %s: %v", *httpAddr, err)
+		}
+		if tlsConfig != nil {
+			listener = tls.NewListener(listener, tlsConfig)
+		}
+		fmt.Printf("✅ Receiving OTLP/HTTP on %s (counters on %s://%s/stats)\n", listener.Addr(), scheme, listener.Addr())
+		go func() {
+			log.Fatal(http.Serve(listener, newHTTPHandler(f, required)))
+		}()
+	}
+
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatalf("❌ Failed to listen on %s: %v", *grpcAddr, err)
+		}
+		var opts []grpc.ServerOption
+		if tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+		server := grpc.NewServer(opts...)
+		registerGRPCServices(server, f, required)
+		fmt.Printf("✅ Receiving OTLP/gRPC on %s (%s)\n", listener.Addr(), security)
+		go func() {
+			log.Fatal(server.Serve(listener))
+		}()
+	}
+
+	if *reportEvery > 0 {
+		go reportStats(*reportEvery)
+	}
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	<-signalChan
+
+	fmt.Println("🛑 Stopping OTLP sink, final counters:")
+	for _, sig := range signals {
+		s := stats[sig.name].snapshot()
+		fmt.Printf("  %-7s %d req, %d resources, %d %s, %d bytes, %d rejected, %d failed\n",
+			sig.name, s.Requests, s.Resources, s.Items, sig.items, s.Bytes, s.Rejected, s.Failed)
+	}
+}
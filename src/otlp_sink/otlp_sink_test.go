@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	logscollectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	tracecollectorpb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hagen-p/o11y-go-loadgen/src/common"
+)
+
+// testRequests returns one request per signal: 2 resources with 3 data points, 1 resource
+// with 2 spans, and 1 resource with 4 log records
+func testRequests() map[string]proto.Message {
+	gauge := func(points int) *metricpb.Metric {
+		dps := make([]*metricpb.NumberDataPoint, points)
+		for i := range dps {
+			dps[i] = &metricpb.NumberDataPoint{Value: &metricpb.NumberDataPoint_AsInt{AsInt: int64(i)}}
+		}
+		return &metricpb.Metric{Name: "test.gauge", Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: dps}}}
+	}
+	return map[string]proto.Message{
+		"metrics": &collectorpb.ExportMetricsServiceRequest{ResourceMetrics: []*metricpb.ResourceMetrics{
+			{ScopeMetrics: []*metricpb.ScopeMetrics{{Metrics: []*metricpb.Metric{gauge(2)}}}},
+			{ScopeMetrics: []*metricpb.ScopeMetrics{{Metrics: []*metricpb.Metric{gauge(1)}}}},
+		}},
+		"traces": &tracecollectorpb.ExportTraceServiceRequest{ResourceSpans: []*tracepb.ResourceSpans{
+			{ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{Name: "a"}, {Name: "b"}}}}},
+		}},
+		"logs": &logscollectorpb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{
+			{ScopeLogs: []*logspb.ScopeLogs{{LogRecords: make([]*logspb.LogRecord, 4)}}},
+		}},
+	}
+}
+
+// startSink serves the OTLP/HTTP handler and the gRPC services on loopback ports and
+// returns the collector URL and the gRPC endpoint
+func startSink(t *testing.T) (string, string) {
+	t.Helper()
+	httpServer := httptest.NewServer(newHTTPHandler(faults{}, requiredHeader{}))
+	t.Cleanup(httpServer.Close)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	registerGRPCServices(grpcServer, faults{}, requiredHeader{})
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return httpServer.URL, listener.Addr().String()
+}
+
+func TestLoadgenSendsToSink(t *testing.T) {
+	collectorURL, grpcEndpoint := startSink(t)
+	common.Sinks = []common.SinkConfig{{Type: common.SinkOTLP}}
+	common.CollectorURL, common.GRPCEndpoint, common.GRPCInsecure = collectorURL, grpcEndpoint, true
+	common.ExportTimeout = 5 * time.Second
+
+	want := map[string]statsSnapshot{
+		"metrics": {Requests: 1, Resources: 2, Items: 3},
+		"traces":  {Requests: 1, Resources: 1, Items: 2},
+		"logs":    {Requests: 1, Resources: 1, Items: 4},
+	}
+	for _, tt := range []struct{ protocol, compression string }{
+		{common.ProtocolHTTPJSON, common.CompressionNone},
+		{common.ProtocolHTTPJSON, common.CompressionGzip},
+		{common.ProtocolHTTPProtobuf, common.CompressionNone},
+		{common.ProtocolHTTPProtobuf, common.CompressionGzip},
+		{common.ProtocolHTTPProtobuf, common.CompressionZstd},
+		{common.ProtocolGRPC, common.CompressionNone},
+		{common.ProtocolGRPC, common.CompressionGzip},
+	} {
+		t.Run(tt.protocol+"+"+tt.compression, func(t *testing.T) {
+			common.Protocol, common.Compression = tt.protocol, tt.compression
+			sink, err := common.NewSink()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sink.Close()
+
+			for signal, req := range testRequests() {
+				before := stats[signal].snapshot()
+				if err := sink.Export(context.Background(), req); err != nil {
+					t.Fatalf("%s: %v", signal, err)
+				}
+				after := stats[signal].snapshot()
+				got := statsSnapshot{
+					Requests:  after.Requests - before.Requests,
+					Rejected:  after.Rejected - before.Rejected,
+					Failed:    after.Failed - before.Failed,
+					Resources: after.Resources - before.Resources,
+					Items:     after.Items - before.Items,
+				}
+				if got != want[signal] {
+					t.Errorf("%s: counted %+v, want %+v", signal, got, want[signal])
+				}
+				if after.Bytes <= before.Bytes {
+					t.Errorf("%s: no bytes counted", signal)
+				}
+			}
+		})
+	}
+}
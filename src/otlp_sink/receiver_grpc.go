@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+
+	logscollectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	tracecollectorpb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// registerGRPCServices adds the OTLP services of all three signals to server
+func registerGRPCServices(server *grpc.Server, f faults, required requiredHeader) {
+	collectorpb.RegisterMetricsServiceServer(server, &metricsService{faults: f, required: required})
+	tracecollectorpb.RegisterTraceServiceServer(server, &traceService{faults: f, required: required})
+	logscollectorpb.RegisterLogsServiceServer(server, &logsService{faults: f, required: required})
+}
+
+// receiveGRPC counts one request or fails it as configured
+func receiveGRPC(ctx context.Context, signal string, f faults, required requiredHeader, req proto.Message) error {
+	if err := required.allowGRPC(ctx, signal); err != nil {
+		return err
+	}
+	if f.apply() {
+		stats[signal].Failed.Add(1)
+		return f.grpcError()
+	}
+	record(signal, req, proto.Size(req))
+	return nil
+}
+
+type metricsService struct {
+	collectorpb.UnimplementedMetricsServiceServer
+	faults   faults
+	required requiredHeader
+}
+
+func (s *metricsService) Export(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	if err := receiveGRPC(ctx, "metrics", s.faults, s.required, req); err != nil {
+		return nil, err
+	}
+	return &collectorpb.ExportMetricsServiceResponse{}, nil
+}
+
+type traceService struct {
+	tracecollectorpb.UnimplementedTraceServiceServer
+	faults   faults
+	required requiredHeader
+}
+
+func (s *traceService) Export(ctx context.Context, req *tracecollectorpb.ExportTraceServiceRequest) (*tracecollectorpb.ExportTraceServiceResponse, error) {
+	if err := receiveGRPC(ctx, "traces", s.faults, s.required, req); err != nil {
+		return nil, err
+	}
+	return &tracecollectorpb.ExportTraceServiceResponse{}, nil
+}
+
+type logsService struct {
+	logscollectorpb.UnimplementedLogsServiceServer
+	faults   faults
+	required requiredHeader
+}
+
+func (s *logsService) Export(ctx context.Context, req *logscollectorpb.ExportLogsServiceRequest) (*logscollectorpb.ExportLogsServiceResponse, error) {
+	if err := receiveGRPC(ctx, "logs", s.faults, s.required, req); err != nil {
+		return nil, err
+	}
+	return &logscollectorpb.ExportLogsServiceResponse{}, nil
+}
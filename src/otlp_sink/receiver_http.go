@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	logscollectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	tracecollectorpb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hagen-p/o11y-go-loadgen/src/common"
+)
+
+// newHTTPHandler serves the OTLP/HTTP paths of all three signals and the counters
+func newHTTPHandler(f faults, required requiredHeader) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/metrics", otlpHandler("metrics", f, required,
+		func() proto.Message { return &collectorpb.ExportMetricsServiceRequest{} },
+		&collectorpb.ExportMetricsServiceResponse{}))
+	mux.HandleFunc("/v1/traces", otlpHandler("traces", f, required,
+		func() proto.Message { return &tracecollectorpb.ExportTraceServiceRequest{} },
+		&tracecollectorpb.ExportTraceServiceResponse{}))
+	mux.HandleFunc("/v1/logs", otlpHandler("logs", f, required,
+		func() proto.Message { return &logscollectorpb.ExportLogsServiceRequest{} },
+		&logscollectorpb.ExportLogsServiceResponse{}))
+	mux.HandleFunc("/stats", serveStats)
+	return mux
+}
+
+// otlpHandler decodes one signal's export requests (JSON or protobuf, optionally gzip or
+// zstd compressed), counts them and answers in the content type of the request
+func otlpHandler(signal string, f faults, required requiredHeader, newRequest func() proto.Message, response proto.Message) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !required.allowHTTP(w, r, signal) {
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			reject(w, signal, "reading body", err)
+			return
+		}
+		decoded, err := common.Decompress(body, r.Header.Get("Content-Encoding"))
+		if err != nil {
+			reject(w, signal, "decompressing body", err)
+			return
+		}
+
+		contentType := r.Header.Get("Content-Type")
+		req := newRequest()
+		if err := common.UnmarshalOTLP(decoded, contentType, req); err != nil {
+			reject(w, signal, "decoding body", err)
+			return
+		}
+
+		if f.apply() {
+			stats[signal].Failed.Add(1)
+			f.writeHTTPError(w)
+			return
+		}
+		record(signal, req, len(body))
+
+		protocol := common.ProtocolHTTPJSON
+		if strings.Contains(contentType, "application/x-protobuf") {
+			protocol = common.ProtocolHTTPProtobuf
+		}
+		payload, responseType, err := common.MarshalOTLP(response, protocol)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", responseType)
+		_, _ = w.Write(payload)
+	}
+}
+
+// reject answers an undecodable request with 400, which the OTLP spec defines as not retryable
+func reject(w http.ResponseWriter, signal string, what string, err error) {
+	stats[signal].Rejected.Add(1)
+	log.Printf("❌ Rejected %s request: %s: %v", signal, what, err)
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
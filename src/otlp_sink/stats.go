@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hagen-p/o11y-go-loadgen/src/common"
+)
+
+// signalStats counts what arrived for one signal. Items are data points for metrics,
+// spans for traces and log records for logs.
+type signalStats struct {
+	Requests  atomic.Int64
+	Rejected  atomic.Int64 // Undecodable requests
+	Failed    atomic.Int64 // Requests answered with an injected error
+	Resources atomic.Int64
+	Items     atomic.Int64
+	Bytes     atomic.Int64 // Body size as received; the decoded size for gRPC
+}
+
+// statsSnapshot is a point-in-time copy of signalStats, also served as JSON
+type statsSnapshot struct {
+	Requests  int64 `json:"requests"`
+	Rejected  int64 `json:"rejected"`
+	Failed    int64 `json:"failed"`
+	Resources int64 `json:"resources"`
+	Items     int64 `json:"items"`
+	Bytes     int64 `json:"bytes"`
+}
+
+func (s *signalStats) snapshot() statsSnapshot {
+	return statsSnapshot{
+		Requests:  s.Requests.Load(),
+		Rejected:  s.Rejected.Load(),
+		Failed:    s.Failed.Load(),
+		Resources: s.Resources.Load(),
+		Items:     s.Items.Load(),
+		Bytes:     s.Bytes.Load(),
+	}
+}
+
+// signals lists the signals in report order, with the name used for their items
+var signals = []struct{ name, items string }{
+	{"metrics", "datapoints"},
+	{"traces", "spans"},
+	{"logs", "records"},
+}
+
+var (
+	started = time.Now()
+	stats   = map[string]*signalStats{"metrics": {}, "traces": {}, "logs": {}}
+)
+
+// record counts an accepted request
+func record(signal string, req proto.Message, size int) {
+	resources, items := common.CountItems(req)
+	s := stats[signal]
+	s.Requests.Add(1)
+	s.Resources.Add(int64(resources))
+	s.Items.Add(int64(items))
+	s.Bytes.Add(int64(size))
+}
+
+// reportStats prints the per second rates since the last report and the totals, for
+// every signal that received anything
+func reportStats(every time.Duration) {
+	last := make(map[string]statsSnapshot)
+	lastTime := time.Now()
+	for range time.Tick(every) {
+		now := time.Now()
+		seconds := now.Sub(lastTime).Seconds()
+		lastTime = now
+		for _, sig := range signals {
+			current := stats[sig.name].snapshot()
+			prev := last[sig.name]
+			last[sig.name] = current
+			if current.Requests+current.Rejected == 0 {
+				continue
+			}
+			fmt.Printf("📊 %-7s %8.1f req/s %10.1f resources/s %12.1f %s/s %10.2f MB/s | total %d req, %d %s, %d rejected, %d failed\n",
+				sig.name,
+				float64(current.Requests-prev.Requests)/seconds,
+				float64(current.Resources-prev.Resources)/seconds,
+				float64(current.Items-prev.Items)/seconds, sig.items,
+				float64(current.Bytes-prev.Bytes)/seconds/(1<<20),
+				current.Requests, current.Items, sig.items, current.Rejected, current.Failed)
+		}
+	}
+}
+
+// serveStats returns the counters of all signals as JSON
+func serveStats(w http.ResponseWriter, _ *http.Request) {
+	body := map[string]any{"uptime_seconds": time.Since(started).Seconds()}
+	for _, sig := range signals {
+		body[sig.name] = stats[sig.name].snapshot()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hagen-p/o11y-go-loadgen/src/common"
+)
+
+// sink receives the replayed traces; built from the sinks section of config.yaml
+var sink common.Sink
+
+// pool runs the replicas of every iteration; created in main once the config is loaded
+var pool *common.ReplicaPool
+
+// processTraceFile replays the trace capture once per replica
+func processTraceFile(filePath string, replicas int) {
+	expandedPath, err := common.ExpandPath(filePath)
+	if err != nil {
+		log.Printf("❌ Failed to expand file path: %v", err)
+		return
+	}
+
+	replacementsFile := filepath.Join(filepath.Dir(expandedPath), "replacements.json")
+	replacements := common.LoadReplacements(replacementsFile)
+
+	log.Printf("📖 Processing trace file: %s", expandedPath)
+
+	data, err := os.ReadFile(expandedPath)
+	if err != nil {
+		log.Printf("❌ Failed to read file: %s, error: %v", expandedPath, err)
+		return
+	}
+
+	var traceFile common.TraceFile
+	if err := json.Unmarshal(data, &traceFile); err != nil {
+		log.Printf("❌ Failed to unmarshal JSON: %v", err)
+		return
+	}
+
+	var iteration sync.WaitGroup
+	for replica := 0; replica < replicas; replica++ {
+		iteration.Add(1)
+		pool.Submit(replica, func() {
+			defer iteration.Done()
+			processTraceReplica(traceFile, replica, replacements)
+		})
+	}
+	iteration.Wait()
+
+	replacements.Save(replacementsFile)
+}
+
+// processTraceReplica turns the capture into a fresh set of traces for one replica and sends it
+func processTraceReplica(traceFile common.TraceFile, replica int, replacements *common.ReplacementMap) {
+	traceCopy := common.DeepCopyTraceFile(traceFile)
+
+	regenerateIDs(&traceCopy)
+	shiftSpanTimes(&traceCopy, time.Now().UnixNano())
+
+	rewriter := common.NewIdentityRewriter(replica, replacements)
+	for i := range traceCopy.ResourceSpans {
+		rewriter.Rewrite(&traceCopy.ResourceSpans[i].Resource)
+	}
+
+	if err := sink.Export(context.Background(), common.ToOTLPTraceRequest(traceCopy)); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
+
+// regenerateIDs replaces every trace and span ID with a random one. The same original ID
+// always maps to the same new ID, so parent/child relations and links stay intact.
+func regenerateIDs(traceFile *common.TraceFile) {
+	traceIDs := make(map[string]string)
+	spanIDs := make(map[string]string)
+
+	// Span IDs are only unique within a trace, so they are keyed by trace and span
+	mapTraceID := func(old string) string { return mapID(traceIDs, old, old, 16) }
+	mapSpanID := func(traceID, old string) string { return mapID(spanIDs, traceID+"/"+old, old, 8) }
+
+	for i := range traceFile.ResourceSpans {
+		for j := range traceFile.ResourceSpans[i].ScopeSpans {
+			spans := traceFile.ResourceSpans[i].ScopeSpans[j].Spans
+			for k := range spans {
+				span := &spans[k]
+				oldTraceID := span.TraceID
+				span.TraceID = mapTraceID(oldTraceID)
+				span.SpanID = mapSpanID(oldTraceID, span.SpanID)
+				span.ParentSpanID = mapSpanID(oldTraceID, span.ParentSpanID)
+				for l := range span.Links {
+					link := &span.Links[l]
+					oldLinkTraceID := link.TraceID
+					link.TraceID = mapTraceID(oldLinkTraceID)
+					link.SpanID = mapSpanID(oldLinkTraceID, link.SpanID)
+				}
+			}
+		}
+	}
+}
+
+// mapID returns the replacement stored under key, generating a random ID of size bytes
+// the first time the key is seen. Empty IDs (root spans) stay empty.
+func mapID(ids map[string]string, key string, old string, size int) string {
+	if old == "" {
+		return ""
+	}
+	if id, ok := ids[key]; ok {
+		return id
+	}
+	raw := make([]byte, size)
+	_, _ = rand.Read(raw)
+	id := hex.EncodeToString(raw)
+	ids[key] = id
+	return id
+}
+
+// shiftSpanTimes moves the whole capture so the earliest span starts at now, which keeps
+// every duration and the relative offsets between spans
+func shiftSpanTimes(traceFile *common.TraceFile, now int64) {
+	var earliest int64
+	for _, rs := range traceFile.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				if start, err := strconv.ParseInt(span.StartTimeUnixNano, 10, 64); err == nil && (earliest == 0 || start < earliest) {
+					earliest = start
+				}
+			}
+		}
+	}
+	if earliest == 0 {
+		log.Printf("⚠️ No valid span start times found, leaving timestamps untouched")
+		return
+	}
+
+	offset := now - earliest
+	for i := range traceFile.ResourceSpans {
+		for j := range traceFile.ResourceSpans[i].ScopeSpans {
+			spans := traceFile.ResourceSpans[i].ScopeSpans[j].Spans
+			for k := range spans {
+				shiftTimestamp(&spans[k].StartTimeUnixNano, offset)
+				shiftTimestamp(&spans[k].EndTimeUnixNano, offset)
+				for e := range spans[k].Events {
+					shiftTimestamp(&spans[k].Events[e].TimeUnixNano, offset)
+				}
+			}
+		}
+	}
+}
+
+func shiftTimestamp(ts *string, offset int64) {
+	if val, err := strconv.ParseInt(*ts, 10, 64); err == nil {
+		*ts = strconv.FormatInt(val+offset, 10)
+	}
+}